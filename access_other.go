@@ -0,0 +1,21 @@
+//go:build !unix
+
+package autocd
+
+import "os"
+
+// access(2) mode bits
+const (
+	accessExecute = 0x1 // X_OK
+	accessWrite   = 0x2 // W_OK
+	accessRead    = 0x4 // R_OK
+)
+
+// checkAccess falls back to opening the path where access(2) is unavailable
+func checkAccess(path string, mode uint32) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,138 @@
+package autocd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// AuditSink selects where transitions are recorded for auditing
+type AuditSink int
+
+const (
+	AuditNone    AuditSink = iota // Default: no audit logging
+	AuditSyslog                   // Local syslog daemon (authpriv.info)
+	AuditJournal                  // systemd journal, falling back to syslog without journald
+)
+
+// auditMessageID is the stable journal MESSAGE_ID of transition records,
+// usable in journalctl filters and message catalogs
+const auditMessageID = "6c1f3a0e9b4d4e2a8f5c7d1b2e3a4f60"
+
+// journalSocket is the native journald datagram socket (replaceable in tests)
+var journalSocket = "/run/systemd/journal/socket"
+
+// auditRecord describes one transition for the audit log
+type auditRecord struct {
+	User      string
+	SudoUser  string
+	App       string
+	PID       int
+	SourceDir string
+	TargetDir string
+	Shell     string
+}
+
+// newAuditRecord collects who spawned which shell where
+func newAuditRecord(result TransitionResult, opts *Options) auditRecord {
+	rec := auditRecord{
+		User:      strconv.Itoa(os.Getuid()),
+		SudoUser:  os.Getenv("SUDO_USER"),
+		App:       appName(opts),
+		PID:       os.Getpid(),
+		TargetDir: result.TargetDir,
+		Shell:     result.ShellPath,
+	}
+	if u, err := user.Current(); err == nil {
+		rec.User = u.Username
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		rec.SourceDir = cwd
+	}
+	return rec
+}
+
+// message renders the record as a single syslog line
+func (r auditRecord) message() string {
+	msg := fmt.Sprintf("transition user=%s app=%s pid=%d source=%q target=%q shell=%s",
+		r.User, r.App, r.PID, r.SourceDir, r.TargetDir, r.Shell)
+	if r.SudoUser != "" {
+		msg += " sudo_user=" + r.SudoUser
+	}
+	return msg
+}
+
+// journalFields returns the structured journal fields for the record
+func (r auditRecord) journalFields(identifier string) [][2]string {
+	fields := [][2]string{
+		{"MESSAGE", r.message()},
+		{"MESSAGE_ID", auditMessageID},
+		{"PRIORITY", "6"},
+		{"SYSLOG_IDENTIFIER", identifier},
+		{"AUTOCD_USER", r.User},
+		{"AUTOCD_APP", r.App},
+		{"AUTOCD_APP_PID", strconv.Itoa(r.PID)},
+		{"AUTOCD_SOURCE_DIR", r.SourceDir},
+		{"AUTOCD_TARGET_DIR", r.TargetDir},
+		{"AUTOCD_SHELL", r.Shell},
+	}
+	if r.SudoUser != "" {
+		fields = append(fields, [2]string{"AUTOCD_SUDO_USER", r.SudoUser})
+	}
+	return fields
+}
+
+// encodeJournalFields serializes fields in the journald native protocol.
+// Values containing newlines use the length-prefixed binary form.
+func encodeJournalFields(fields [][2]string) []byte {
+	var buf bytes.Buffer
+	for _, f := range fields {
+		name, value := f[0], f[1]
+		if !strings.Contains(value, "\n") {
+			buf.WriteString(name + "=" + value + "\n")
+			continue
+		}
+		buf.WriteString(name + "\n")
+		binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value + "\n")
+	}
+	return buf.Bytes()
+}
+
+// auditTransition records a transition in the configured sink
+func auditTransition(result TransitionResult, opts *Options) error {
+	if opts.Audit == AuditNone {
+		return nil
+	}
+
+	identifier := opts.AuditIdentifier
+	if identifier == "" {
+		identifier = "autocd"
+	}
+	rec := newAuditRecord(result, opts)
+
+	if opts.Audit == AuditJournal {
+		if err := sendJournal(encodeJournalFields(rec.journalFields(identifier))); err == nil {
+			return nil
+		}
+	}
+	return sendSyslog(identifier, rec.message())
+}
+
+// sendJournal writes one entry to the journald socket
+func sendJournal(entry []byte) error {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(entry); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
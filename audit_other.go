@@ -0,0 +1,13 @@
+//go:build !unix
+
+package autocd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// sendSyslog fails where there is no local syslog daemon
+func sendSyslog(identifier, message string) error {
+	return fmt.Errorf("syslog is not available on %s", runtime.GOOS)
+}
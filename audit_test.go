@@ -0,0 +1,69 @@
+package autocd
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the journald native protocol encoding
+func TestEncodeJournalFields(t *testing.T) {
+	data := encodeJournalFields([][2]string{{"MESSAGE", "hello"}, {"AUTOCD_TARGET_DIR", "/tmp/a\nb"}})
+
+	expected := "MESSAGE=hello\nAUTOCD_TARGET_DIR\n\x08\x00\x00\x00\x00\x00\x00\x00/tmp/a\nb\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+// Test that journal audit records reach the socket with stable fields
+func TestAuditTransition_Journal(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	original := journalSocket
+	journalSocket = socket
+	defer func() { journalSocket = original }()
+
+	result := TransitionResult{TargetDir: "/tmp/project", ShellPath: "/bin/bash"}
+	if err := auditTransition(result, &Options{Audit: AuditJournal, AuditIdentifier: "mytool"}); err != nil {
+		t.Fatalf("auditTransition failed: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("No journal entry received: %v", err)
+	}
+	entry := string(buf[:n])
+	for _, field := range []string{"SYSLOG_IDENTIFIER=mytool\n", "MESSAGE_ID=" + auditMessageID + "\n", "AUTOCD_TARGET_DIR=/tmp/project\n", "AUTOCD_SHELL=/bin/bash\n"} {
+		if !strings.Contains(entry, field) {
+			t.Errorf("Journal entry missing %q:\n%s", field, entry)
+		}
+	}
+}
+
+// Test the one-line syslog rendering
+func TestAuditRecordMessage(t *testing.T) {
+	rec := auditRecord{User: "alice", SudoUser: "bob", App: "fm", PID: 42, SourceDir: "/src", TargetDir: "/dst dir", Shell: "/bin/zsh"}
+	msg := rec.message()
+	expected := `transition user=alice app=fm pid=42 source="/src" target="/dst dir" shell=/bin/zsh sudo_user=bob`
+	if msg != expected {
+		t.Errorf("Expected %q, got %q", expected, msg)
+	}
+
+	if !bytes.Contains(encodeJournalFields(rec.journalFields("autocd")), []byte("AUTOCD_SUDO_USER=bob\n")) {
+		t.Error("Journal fields should include the sudo user")
+	}
+	if err := auditTransition(TransitionResult{}, &Options{}); err != nil {
+		t.Errorf("Disabled audit should be a no-op: %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package autocd
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// sendSyslog writes one message to the local syslog daemon
+func sendSyslog(identifier, message string) error {
+	w, err := syslog.New(syslog.LOG_AUTHPRIV|syslog.LOG_INFO, identifier)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	defer w.Close()
+	return w.Info(message)
+}
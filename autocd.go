@@ -1,38 +1,45 @@
 package autocd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 )
 
 // checkShellDepth examines the current shell nesting level and displays
-// helpful warnings when appropriate based on platform capabilities
-func checkShellDepth(opts *Options) {
+// helpful warnings when appropriate based on platform capabilities. With
+// rate limiting configured it returns the marker to export into the
+// spawned shell, so later transitions in the session know when the tip was
+// last shown.
+func checkShellDepth(opts *Options) []envVar {
 	// Skip if warnings are disabled
 	if opts.DisableDepthWarnings {
-		return
+		return nil
 	}
 
-	// Unix systems: Check SHLVL environment variable
-	shlvlStr := os.Getenv("SHLVL")
-	if shlvlStr == "" {
-		// No SHLVL means likely not in a shell or shell doesn't support it
-		return
-	}
+	prev := os.Getenv(depthTipEnv)
+	shown := false
 
-	shlvl, err := strconv.Atoi(shlvlStr)
-	if err != nil {
-		// Invalid SHLVL value, skip warning
-		return
-	}
+	// SHLVL when trustworthy, otherwise the process ancestry
+	shlvl := ShellDepth()
 
 	// Show warning if above threshold
-	if shlvl >= opts.DepthWarningThreshold {
+	if shlvl > 0 && shlvl >= opts.DepthWarningThreshold && !depthLimitReached(shlvl, opts) && depthTipDue(prev, opts) {
 		fmt.Fprintf(os.Stderr, "💡 Tip: You have %d nested shells from navigation.\n", shlvl)
 		fmt.Fprintf(os.Stderr, "For better performance, consider opening a fresh terminal.\n")
+		shown = true
+	}
+
+	if opts.DepthWarningInterval <= 0 && !opts.DepthWarningOnce {
+		return nil
+	}
+	if marker := nextDepthTipMarker(prev, shown); marker != "" {
+		return []envVar{{Name: depthTipEnv, Value: marker}}
 	}
+	return nil
 }
 
 // ExitWithDirectory inherits the target directory to the parent shell when the process exits.
@@ -54,6 +61,82 @@ func ExitWithDirectory(targetPath string) error {
 	return ExitWithDirectoryAdvanced(targetPath, nil)
 }
 
+// ExitWithRelativeDirectory is ExitWithDirectory for a path relative to
+// the current working directory, e.g. "src/pkg" or "../sibling".
+func ExitWithRelativeDirectory(relPath string) error {
+	targetPath, err := relativeTarget(relPath)
+	if err != nil {
+		return newPathValidationError(relPath, err)
+	}
+	return ExitWithDirectory(targetPath)
+}
+
+// ExitWithParentDirectory is ExitWithDirectory for the directory levels
+// above the current working directory (0 is the working directory itself).
+// Climbing stops at the filesystem root.
+func ExitWithParentDirectory(levels int) error {
+	targetPath, err := parentTarget(levels)
+	if err != nil {
+		return newPathValidationError(strconv.Itoa(levels), err)
+	}
+	return ExitWithDirectory(targetPath)
+}
+
+// ExitWithProcessDirectory is ExitWithDirectory for the current working
+// directory of another process, e.g. a worker started by a supervisor.
+func ExitWithProcessDirectory(pid int) error {
+	targetPath, err := GetProcessCWD(pid)
+	if err != nil {
+		return newPathValidationError(strconv.Itoa(pid), err)
+	}
+	return ExitWithDirectory(targetPath)
+}
+
+// GetProcessCWD returns the current working directory of the process with
+// the given PID. It reads /proc on Linux and asks lsof elsewhere; reading
+// another user's process usually requires privileges.
+func GetProcessCWD(pid int) (string, error) {
+	if pid <= 0 {
+		return "", fmt.Errorf("invalid pid %d", pid)
+	}
+	cwd, err := processCWD(pid)
+	if err != nil {
+		return "", fmt.Errorf("failed to read working directory of pid %d: %w", pid, err)
+	}
+	return cwd, nil
+}
+
+// relativeTarget joins relPath onto the working directory
+func relativeTarget(relPath string) (string, error) {
+	if filepath.IsAbs(relPath) {
+		return "", fmt.Errorf("path %q is not relative", relPath)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return filepath.Join(cwd, relPath), nil
+}
+
+// parentTarget walks levels directories up from the working directory
+func parentTarget(levels int) (string, error) {
+	if levels < 0 {
+		return "", fmt.Errorf("invalid parent level %d", levels)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	for i := 0; i < levels; i++ {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dir, nil
+}
+
 // ExitWithDirectoryAdvanced provides advanced configuration options for directory inheritance.
 // This function offers the same core functionality as ExitWithDirectory but with additional
 // control over security levels, shell detection, debug mode, and other options.
@@ -75,76 +158,489 @@ func ExitWithDirectory(targetPath string) error {
 //		os.Exit(1)
 //	}
 func ExitWithDirectoryAdvanced(targetPath string, opts *Options) error {
+	return exitWithDirectory(targetPath, opts, nil)
+}
+
+// ExitWithDirectoryAndCommand is ExitWithDirectoryAdvanced with a command
+// run in the target directory before the interactive shell starts, such as
+// "git status" or an editor. The command is interpreted by /bin/sh and its
+// exit status is exported as AUTOCD_COMMAND_STATUS. opts may be nil.
+func ExitWithDirectoryAndCommand(targetPath, command string, opts *Options) error {
+	withCommand := *withDefaults(opts)
+	withCommand.PostCommand = command
+	return exitWithDirectory(targetPath, &withCommand, nil)
+}
+
+// ExitWithDirectoryUsingShell is ExitWithDirectory with a shell chosen by
+// the application (e.g. from its own settings or an earlier
+// GetCurrentShellInfo call) instead of shell detection. The shell's Path
+// must be an executable file; its Args are passed to it.
+func ExitWithDirectoryUsingShell(targetPath string, shell *ShellInfo) error {
+	if shell == nil {
+		return newShellDetectionError("shell info is nil")
+	}
+	return exitWithDirectory(targetPath, nil, shell)
+}
+
+// withDefaults returns opts with unset fields defaulted, or the default
+// options when opts is nil
+func withDefaults(opts *Options) *Options {
 	// Set defaults if options not provided
 	if opts == nil {
 		opts = &Options{
 			SecurityLevel:         SecurityNormal,
 			DebugMode:             os.Getenv("AUTOCD_DEBUG") != "",
-			DepthWarningThreshold: 15,
+			DepthWarningThreshold: defaultDepthWarningThreshold(),
 			DisableDepthWarnings:  false,
 		}
 	}
 
 	// Set defaults for new fields if not specified
 	if opts.DepthWarningThreshold == 0 {
-		opts.DepthWarningThreshold = 15
+		opts.DepthWarningThreshold = defaultDepthWarningThreshold()
+	}
+	return opts
+}
+
+// exitWithDirectory performs the transition; presetShell, when non-nil,
+// replaces shell detection
+func exitWithDirectory(targetPath string, opts *Options, presetShell *ShellInfo) error {
+	opts = withDefaults(opts)
+	if opts.DryRun {
+		script, _, err := GenerateTransitionScript(targetPath, opts)
+		if err == nil {
+			fmt.Fprint(os.Stdout, script)
+		}
+		return err
+	}
+	if len(opts.Strategies) > 0 {
+		return runStrategies(targetPath, opts, presetShell)
+	}
+	return execTransition(targetPath, opts, presetShell)
+}
+
+// execTransition prepares the transition and replaces the process with the
+// transition script (or the shell itself)
+func execTransition(targetPath string, opts *Options, presetShell *ShellInfo) error {
+	t, err := prepareTransition(targetPath, opts, presetShell, true)
+	if err != nil {
+		return err
+	}
+	validatedPath, shell, timer := t.TargetDir, t.Shell, t.Timer
+
+	if err := t.commit(opts); err != nil {
+		return err
+	}
+
+	// Give the background sweep a moment to finish; exec abandons it
+	ownGoroutines := 0
+	if !waitCleanup(t.CleanupDone, cleanupGracePeriod) {
+		ownGoroutines++
+	}
+
+	// Exec silently discards other goroutines and buffered output
+	if err := checkPendingWork(opts, ownGoroutines); err != nil {
+		t.discard()
+		return newScriptExecutionError(err)
+	}
+
+	// 7. Execute script (this should never return)
+	reportStrategy(opts, StrategyExec, nil)
+	if t.Direct {
+		err = execShellDirect(validatedPath, shell, opts, t.Launch)
+	} else {
+		err = execReplacement(t.ScriptPath, shell, opts)
+	}
+	timer.mark("exec")
+	if opts.DebugMode {
+		fmt.Fprintf(os.Stderr, "autocd: timings: %s\n", timer)
+	}
+
+	// If we reach here, execution failed
+	t.discard() // Cleanup on failure
+	return newScriptExecutionError(err)
+}
+
+// transition is a prepared transition: the validated target, the shell
+// and the script or launch artifacts starting it
+type transition struct {
+	TargetDir   string
+	Shell       *ShellInfo
+	Launch      *shellLaunch
+	ScriptPath  string          // "" when Direct
+	Direct      bool            // Exec the shell without a script
+	Release     func()          // Removes the script
+	Timer       *phaseTimer     // Phases measured so far
+	CleanupDone <-chan struct{} // Closed when the background cleanup sweep finishes
+}
+
+// discard removes the script and launch artifacts of an unused transition
+func (t *transition) discard() {
+	t.Release()
+	t.Launch.remove()
+}
+
+// commit runs the steps between preparing a transition and starting its
+// shell, whether by exec or as a child: the application's veto, clipboard
+// and tmux exports, the result report and the audit record. A veto
+// discards the transition.
+func (t *transition) commit(opts *Options) error {
+	// Last-moment veto by the application (unsaved work, confirmation)
+	if opts.OnBeforeExec != nil {
+		if err := opts.OnBeforeExec(Transition{TargetDir: t.TargetDir, Shell: t.Shell, ScriptPath: t.ScriptPath}); err != nil {
+			t.discard()
+			return newVetoError(err)
+		}
+	}
+
+	// Copy the path first so the user has it even if exec fails
+	if opts.CopyToClipboard {
+		if err := copyToClipboard(t.TargetDir); err != nil && opts.DebugMode {
+			fmt.Fprintf(os.Stderr, "autocd: clipboard warning: %v\n", err)
+		}
+	}
+
+	// Let panes and windows created later see the directory
+	if opts.TmuxExport || opts.TmuxPaneTitle != "" {
+		if err := exportToTmux(t.TargetDir, opts); err != nil && opts.DebugMode {
+			fmt.Fprintf(os.Stderr, "autocd: tmux warning: %v\n", err)
+		}
+	}
+
+	if opts.DebugMode {
+		fmt.Fprintf(os.Stderr, "autocd: timings: %s\n", t.Timer)
+	}
+
+	// Report the transition to wrappers/supervisors (non-fatal)
+	result := TransitionResult{
+		TargetDir:  t.TargetDir,
+		ShellPath:  t.Shell.Path,
+		ScriptPath: t.ScriptPath,
+		Timestamp:  time.Now(),
+		Phases:     t.Timer.phases,
+	}
+	if err := writeTransitionResult(result, opts); err != nil && opts.DebugMode {
+		fmt.Fprintf(os.Stderr, "autocd: result warning: %v\n", err)
+	}
+
+	// Audit the shell spawn; failures are always reported since the
+	// application opted in, but do not block the transition
+	if err := auditTransition(result, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "autocd: audit warning: %v\n", err)
+	}
+	return nil
+}
+
+// prepareTransition runs everything up to exec: target resolution and
+// validation, shell detection, checks and writing the script. When
+// exiting, the wrapper protocols and the file manager fallback may end the
+// process instead, and the shell may be exec'd directly.
+func prepareTransition(targetPath string, opts *Options, presetShell *ShellInfo, exiting bool) (*transition, error) {
+	timer := newPhaseTimer(opts.PrepareTimeout)
+
+	// Symbolic targets ("bookmark:work") go through their registered resolver
+	resolvedPath, err := resolveTarget(targetPath)
+	if err != nil {
+		return nil, newPathValidationError(targetPath, err)
+	}
+	targetPath = resolvedPath
+
+	// Setuid/setgid processes must not trust the invoking user's TMPDIR.
+	// The private directory goes into a copy so the caller's Options stay
+	// untouched (and safe to share between goroutines).
+	if isPrivileged() {
+		privateDir, err := privilegedTempDir(opts.TempDir)
+		if err != nil {
+			return nil, newSecurityError(opts.TempDir, err)
+		}
+		private := *opts
+		private.TempDir = privateDir
+		opts = &private
 	}
 
 	// Check shell depth and show helpful warnings if appropriate
-	checkShellDepth(opts)
+	depthMarker := checkShellDepth(opts)
 
-	// 1. Clean up old temporary scripts from previous runs
-	if err := cleanupOldScripts(1 * time.Hour); err != nil {
-		// Non-fatal error - log if debug mode but continue
-		if opts.DebugMode {
-			fmt.Fprintf(os.Stderr, "autocd: cleanup warning: %v\n", err)
+	// 1. Clean up old temporary scripts from previous runs in the
+	// background, so a huge /tmp never stalls the transition
+	cleanupDone := startCleanup(opts)
+
+	// Keep the autocd temp area bounded on shared machines
+	if err := enforceScriptQuota(GetTempDir(opts.TempDir), opts); err != nil {
+		return nil, newScriptCreationError(err)
+	}
+
+	timer.mark("cleanup")
+	if err := timer.overBudget(); err != nil {
+		return nil, newTimeoutError(err)
+	}
+
+	// A background job (clone, mount, build) may still be creating the target
+	if opts.WaitForTarget > 0 {
+		err := waitForDirectory(targetPath, timer.capped(opts.WaitForTarget))
+		timer.mark("wait")
+		if budgetErr := timer.overBudget(); budgetErr != nil {
+			return nil, newTimeoutError(budgetErr)
+		}
+		missingAllowed := opts.SecurityLevel == SecurityPermissive && opts.AllowMissingTarget
+		if err != nil && !(errors.Is(err, ErrPathNotFound) && missingAllowed) {
+			return nil, newPathValidationError(targetPath, err)
 		}
 	}
 
-	// If a custom temp dir is specified, clean it as well
-	if opts.TempDir != "" && DirectoryExists(opts.TempDir) {
-		if err := cleanupOldScriptsInDir(opts.TempDir, 1*time.Hour); err != nil {
-			if opts.DebugMode {
-				fmt.Fprintf(os.Stderr, "autocd: cleanup (custom temp) warning: %v\n", err)
-			}
+	// Automounted homes and shares report ENOENT until they are accessed
+	if opts.AutomountTimeout > 0 {
+		if err := triggerAutomount(targetPath, timer.capped(opts.AutomountTimeout)); err != nil && opts.DebugMode {
+			fmt.Fprintf(os.Stderr, "autocd: automount warning: %v\n", err)
+		}
+		timer.mark("automount")
+		if err := timer.overBudget(); err != nil {
+			return nil, newTimeoutError(err)
 		}
 	}
 
 	// 2. Validate target directory
 	validatedPath, err := validateTargetPath(targetPath, opts.SecurityLevel)
+	if errors.Is(err, ErrPathNotFound) && opts.SecurityLevel == SecurityPermissive && opts.AllowMissingTarget {
+		// Another process may create the directory before the shell starts
+		validatedPath, err = validateMissingTarget(targetPath)
+	}
+	if err == nil && opts.PolicyFile != "" {
+		err = enforcePolicyFile(opts.PolicyFile, validatedPath, true)
+	}
 	if err != nil {
-		return newPathValidationError(targetPath, err)
+		return nil, newPathValidationError(targetPath, err)
+	}
+	timer.mark("validation")
+	if err := timer.overBudget(); err != nil {
+		return nil, newTimeoutError(err)
+	}
+
+	// Wrapper protocols replace the shell when the process is exiting
+	if exiting {
+		// Wrapper protocol: hand the directory to the user's shell function and
+		// exit normally instead of spawning a nested shell
+		if opts.CDFileEnv != "" {
+			handled, err := writeCDFileFromEnv(validatedPath, opts.CDFileEnv)
+			if err != nil {
+				return nil, err
+			}
+			if handled {
+				exitProcess(opts.AppExitStatus)
+			}
+		}
+
+		// broot-style protocol: write a complete shell command and exit
+		handled, err := writeOutCmd(validatedPath, opts)
+		if err != nil {
+			return nil, err
+		}
+		if handled {
+			exitProcess(opts.AppExitStatus)
+		}
+
+		// lf/ranger protocol: write the plain directory for the wrapper and exit
+		if file := lastDirPath(opts); file != "" {
+			if err := writeLastDirFile(validatedPath, file); err != nil {
+				return nil, err
+			}
+			exitProcess(opts.AppExitStatus)
+		}
+
+		// Refuse or collapse another nesting level past the hard limit
+		if err := enforceDepthLimit(validatedPath, opts); err != nil {
+			return nil, err
+		}
 	}
 
-	// 3. Detect shell
-	shell := detectShell(opts.Shell)
+	// Under sudo, the shell can be handed back to the invoking user
+	invoker := detectSudoInvoker()
+	handoff := invoker != nil && opts.SudoHandoff
+
+	// Apply the root-execution policy (e.g. tool accidentally run under sudo)
+	if os.Geteuid() == 0 && !handoff {
+		if opts.RefuseAsRoot {
+			return nil, newSecurityError(validatedPath, ErrRunningAsRoot)
+		}
+		if opts.WarnAsRoot {
+			warnRootShell(validatedPath, invoker, opts)
+		}
+	}
+
+	// 3. Detect shell, unless the application chose one
+	var shell *ShellInfo
+	if presetShell != nil {
+		shell = &ShellInfo{
+			Path:    presetShell.Path,
+			IsValid: fileExists(presetShell.Path),
+			Args:    append([]string{}, presetShell.Args...),
+		}
+		if !shell.IsValid {
+			return nil, newShellDetectionError(fmt.Sprintf("provided shell %q is not an executable file", shell.Path))
+		}
+	} else {
+		// Optionally skip shells that can't actually run interactively
+		var accept func(*ShellInfo) bool
+		if opts.ShellProbeTimeout > 0 {
+			accept = func(candidate *ShellInfo) bool {
+				err := probeInteractiveShell(candidate, opts.ShellProbeTimeout)
+				if err != nil && opts.DebugMode {
+					fmt.Fprintf(os.Stderr, "autocd: skipping shell: %v\n", err)
+				}
+				return err == nil
+			}
+		}
+		shell = detectShellAccepted(opts.Shell, opts.DetectionOrder, accept)
+
+		// A handed-off shell is the invoking user's login shell, not root's
+		if handoff && opts.Shell == "" {
+			if userShell := invoker.loginShell(); userShell != nil {
+				shell = userShell
+			}
+		}
+	}
 
 	if !shell.IsValid {
-		return newShellDetectionError("no valid shell found")
+		if opts.ShellProbeTimeout > 0 && fileExists(shell.Path) {
+			return nil, newShellDetectionError(fmt.Sprintf("no shell passed the interactive probe (first rejected: %s)", shell.Path))
+		}
+		return nil, newShellDetectionError(describeMissingShell(shell, opts.Shell))
+	}
+
+	// Under strict security, shell overrides must be registered login shells
+	if opts.SecurityLevel == SecurityStrict && (opts.Shell != "" || presetShell != nil) {
+		if err := checkAllowedShell(shell.Path, opts.ShellsFile); err != nil {
+			return nil, newShellSecurityError(shell.Path, err)
+		}
 	}
 
 	if opts.DebugMode {
 		fmt.Fprintf(os.Stderr, "autocd: shell=%s\n", shell.Path)
 	}
+	timer.mark("detection")
 
-	// 4. Generate appropriate script
-	scriptContent, err := generateScript(validatedPath, shell)
-	if err != nil {
-		return newScriptGenerationError(err)
+	// An interactive shell without a terminal misbehaves ("no job control")
+	if !opts.SkipTTYCheck {
+		if err := checkTerminal(opts.ReopenTTY, opts.RequireTerminalOutput); err != nil {
+			// Without a terminal (e.g. launched from a GUI), still take the
+			// user there by showing the directory in the file manager
+			if exiting && opts.OpenInFileManager {
+				openErr := openInFileManager(validatedPath)
+				if openErr == nil {
+					exitProcess(opts.AppExitStatus)
+				}
+				if opts.DebugMode {
+					fmt.Fprintf(os.Stderr, "autocd: file manager fallback failed: %v\n", openErr)
+				}
+			}
+			// Run from a script or pipeline: keep the status visible to
+			// the caller rather than failing the transition
+			if exiting && opts.ExitIfNonInteractive {
+				exitProcess(opts.AppExitStatus)
+			}
+			return nil, newTerminalError(err)
+		}
+	}
+	timer.mark("terminal")
+	if err := timer.overBudget(); err != nil {
+		return nil, newTimeoutError(err)
+	}
+
+	// Variables added by the application must be plain names
+	if err := checkExtraEnv(opts); err != nil {
+		return nil, newScriptGenerationError(err)
 	}
 
-	// 5. Write script to temporary file
-	scriptPath, err := createTemporaryScript(scriptContent, ".sh", opts.TempDir)
+	// 4. Prepare rc injection for shell customizations
+	launch, err := prepareShellLaunch(shell, opts)
 	if err != nil {
-		return newScriptCreationError(err)
+		return nil, newScriptCreationError(err)
+	}
+	launch.Env = append(launch.Env, depthMarker...)
+	if handoff {
+		if opts.DebugMode {
+			fmt.Fprintf(os.Stderr, "autocd: handing shell to sudo user %s\n", invoker.Name)
+		}
+		launch.RunAs = invoker
+		if err := launch.chown(invoker.UID, invoker.GID); err != nil {
+			launch.remove()
+			return nil, newScriptCreationError(err)
+		}
 	}
 
-	// 6. Execute script (this should never return)
-	err = ExecReplacement(scriptPath, shell, opts.DebugMode)
+	// Quitting without navigating needs no script: exec the shell directly
+	direct := exiting && opts.DirectExecSameDir && sameDirectoryExec(validatedPath, opts, launch)
 
-	// If we reach here, execution failed
-	os.Remove(scriptPath) // Cleanup on failure
-	return newScriptExecutionError(err)
+	// 5-6. Generate the script and write it to a temporary file
+	scriptPath, releaseScript := "", func() {}
+	if !direct {
+		scriptPath, releaseScript, err = buildScript(validatedPath, shell, opts, launch, timer)
+		if err != nil {
+			launch.remove()
+			return nil, err
+		}
+	}
+
+	// The target can vanish after validation (slow TUIs, network mounts);
+	// check again and apply the missing-target policy before exec
+	if !direct && opts.RevalidateTarget && !opts.AllowMissingTarget {
+		finalPath, err := revalidateTarget(validatedPath, opts)
+		if err == nil && finalPath != validatedPath && opts.PolicyFile != "" {
+			err = enforcePolicyFile(opts.PolicyFile, finalPath, true)
+		}
+		if err != nil {
+			releaseScript()
+			launch.remove()
+			return nil, newPathValidationError(validatedPath, err)
+		}
+		if finalPath != validatedPath {
+			if opts.DebugMode {
+				fmt.Fprintf(os.Stderr, "autocd: %s disappeared, using %s\n", validatedPath, finalPath)
+			}
+			releaseScript()
+			validatedPath = finalPath
+			scriptPath, releaseScript, err = buildScript(validatedPath, shell, opts, launch, timer)
+			if err != nil {
+				launch.remove()
+				return nil, err
+			}
+		}
+		timer.mark("revalidation")
+	}
+
+	if err := timer.overBudget(); err != nil {
+		releaseScript()
+		launch.remove()
+		return nil, newTimeoutError(err)
+	}
+
+	return &transition{
+		TargetDir:   validatedPath,
+		Shell:       shell,
+		Launch:      launch,
+		ScriptPath:  scriptPath,
+		Direct:      direct,
+		Release:     releaseScript,
+		Timer:       timer,
+		CleanupDone: cleanupDone,
+	}, nil
+}
+
+// buildScript generates the transition script for targetDir and writes it
+// to a temporary file, returning its path and release function
+func buildScript(targetDir string, shell *ShellInfo, opts *Options, launch *shellLaunch, timer *phaseTimer) (string, func(), error) {
+	scriptContent, err := generateScript(targetDir, shell, opts, launch)
+	if err != nil {
+		return "", nil, newScriptGenerationError(err)
+	}
+	timer.mark("generation")
+
+	scriptPath, releaseScript, err := writeScript(scriptContent, opts)
+	if err != nil {
+		return "", nil, newScriptCreationError(err)
+	}
+	timer.mark("write")
+	return scriptPath, releaseScript, nil
 }
 
 // ExitWithDirectoryOrFallback guarantees process exit
@@ -174,9 +670,38 @@ func GetCurrentShellInfo() *ShellInfo {
 
 // ValidateDirectory checks if a directory is valid for autocd without executing
 func ValidateDirectory(targetPath string, securityLevel SecurityLevel) error {
-	_, err := validateTargetPath(targetPath, securityLevel)
+	_, err := resolveAndValidate(targetPath, securityLevel)
 	if err != nil {
 		return newPathValidationError(targetPath, err)
 	}
 	return nil
 }
+
+// resolveAndValidate expands a symbolic target and validates the result
+func resolveAndValidate(targetPath string, securityLevel SecurityLevel) (string, error) {
+	resolvedPath, err := resolveTarget(targetPath)
+	if err != nil {
+		return "", err
+	}
+	return validateTargetPath(resolvedPath, securityLevel)
+}
+
+// ResolveDirectory validates a directory like ValidateDirectory and returns
+// the cleaned absolute path autocd would cd into, so applications can show
+// exactly where the user will end up. With resolveSymlinks set, symbolic
+// links in the validated path are resolved as well.
+func ResolveDirectory(targetPath string, securityLevel SecurityLevel, resolveSymlinks bool) (string, error) {
+	validatedPath, err := resolveAndValidate(targetPath, securityLevel)
+	if err != nil {
+		return "", newPathValidationError(targetPath, err)
+	}
+
+	if resolveSymlinks {
+		realPath, err := filepath.EvalSymlinks(validatedPath)
+		if err != nil {
+			return "", newPathValidationError(targetPath, fmt.Errorf("failed to resolve symlinks: %w", err))
+		}
+		return realPath, nil
+	}
+	return validatedPath, nil
+}
@@ -3,6 +3,7 @@ package autocd
 import (
 	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -41,6 +42,89 @@ func TestValidateDirectory_FileInsteadOfDirectory(t *testing.T) {
 	}
 }
 
+// Test ResolveDirectory returns the cleaned and optionally real path
+func TestResolveDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	realDir := filepath.Join(tempDir, "real")
+	if err := os.Mkdir(realDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	link := filepath.Join(tempDir, "link")
+	if err := os.Symlink(realDir, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	expectedReal, err := filepath.EvalSymlinks(realDir)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", realDir, err)
+	}
+
+	resolved, err := ResolveDirectory(link+"/./", SecurityNormal, false)
+	if err != nil {
+		t.Fatalf("ResolveDirectory failed: %v", err)
+	}
+	if resolved != link {
+		t.Errorf("Expected cleaned path %s, got %s", link, resolved)
+	}
+
+	resolved, err = ResolveDirectory(link, SecurityNormal, true)
+	if err != nil {
+		t.Fatalf("ResolveDirectory failed: %v", err)
+	}
+	if resolved != expectedReal {
+		t.Errorf("Expected real path %s, got %s", expectedReal, resolved)
+	}
+
+	_, err = ResolveDirectory(filepath.Join(tempDir, "missing"), SecurityNormal, true)
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got: %v", err)
+	}
+}
+
+// Test relative and parent target resolution
+func TestRelativeAndParentTargets(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	target, err := relativeTarget("a/../b")
+	if err != nil || target != filepath.Join(cwd, "b") {
+		t.Errorf("relativeTarget: got %q, %v", target, err)
+	}
+	if _, err := relativeTarget("/abs"); err == nil {
+		t.Error("Expected absolute path to be rejected")
+	}
+
+	target, err = parentTarget(0)
+	if err != nil || target != cwd {
+		t.Errorf("parentTarget(0): got %q, %v", target, err)
+	}
+	target, err = parentTarget(1)
+	if err != nil || target != filepath.Dir(cwd) {
+		t.Errorf("parentTarget(1): got %q, %v", target, err)
+	}
+	target, err = parentTarget(1000)
+	if err != nil || target != filepath.Dir(target) {
+		t.Errorf("parentTarget(1000): expected filesystem root, got %q, %v", target, err)
+	}
+	if _, err := parentTarget(-1); err == nil {
+		t.Error("Expected negative level to be rejected")
+	}
+}
+
+// Test relative-path entry points fail without exec on bad input
+func TestExitWithRelativeAndParentDirectory_Errors(t *testing.T) {
+	if err := ExitWithRelativeDirectory("/abs"); !IsPathError(err) {
+		t.Errorf("Expected path error for absolute path, got: %v", err)
+	}
+	if err := ExitWithRelativeDirectory("nonexistent_autocd_test_dir"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got: %v", err)
+	}
+	if err := ExitWithParentDirectory(-1); !IsPathError(err) {
+		t.Errorf("Expected path error for negative level, got: %v", err)
+	}
+}
+
 // Test security levels
 func TestPathValidation_SecurityLevels(t *testing.T) {
 	tempDir := os.TempDir()
@@ -96,6 +180,74 @@ func TestPathValidation_DangerousCharacters(t *testing.T) {
 	})
 }
 
+// Test that normal mode rejects line breaks with a precise error and
+// leaves other control characters to the message escaping
+func TestValidateNormal_ControlCharacters(t *testing.T) {
+	for _, path := range []string{"/tmp/a\nb", "/tmp/a\rb"} {
+		_, err := validateNormal(path)
+		if !errors.Is(err, ErrControlCharacter) || !errors.Is(err, ErrSecurityViolation) {
+			t.Errorf("validateNormal(%q) should fail with ErrControlCharacter, got: %v", path, err)
+		}
+	}
+	for _, path := range []string{"/tmp/a\x1b[31mb", "/tmp/a\tb"} {
+		if _, err := validateNormal(path); err != nil {
+			t.Errorf("validateNormal(%q) should be accepted, got: %v", path, err)
+		}
+	}
+
+	_, err := validateNormal("/tmp/a\nb")
+	if err == nil || !strings.Contains(err.Error(), `'\n' at byte 6`) {
+		t.Errorf("Expected the offending character and offset in the error, got: %v", err)
+	}
+
+	if got := escapeControlChars("/tmp/a\nb\r\tc\x1bd"); got != `/tmp/a\nb\r\tc\x1bd` {
+		t.Errorf("Unexpected escaped path: %q", got)
+	}
+}
+
+// Test that permissive mode keeps messages on one line for newline paths
+func TestTransitionScript_ControlCharacterPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "evil\nDirectory changed to: elsewhere")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Skipf("filesystem rejects newlines in names: %v", err)
+	}
+
+	output := runTransitionScript(t, dir, &Options{PlainOutput: true})
+	banner := "Directory changed to: " + escapeControlChars(dir) + "\n"
+	if !strings.HasPrefix(output, banner) {
+		t.Errorf("Expected an escaped single-line banner, got:\n%s", output)
+	}
+	if !strings.Contains(output, "AUTOCD_TARGET_DIR="+dir+"\n") {
+		t.Error("The exported target should keep the real directory name")
+	}
+}
+
+// Test that stale transition scripts refuse to run
+func TestTransitionScript_Freshness(t *testing.T) {
+	now := time.Now().Unix()
+
+	fresh := renderFreshnessCheck(now, 300) + "echo ran\n"
+	if output, err := exec.Command("/bin/sh", "-c", fresh).Output(); err != nil || string(output) != "ran\n" {
+		t.Errorf("A fresh script should run, got %q (%v)", output, err)
+	}
+
+	stale := renderFreshnessCheck(now-600, 300) + "echo ran\n"
+	output, err := exec.Command("/bin/sh", "-c", stale).CombinedOutput()
+	if err == nil || strings.Contains(string(output), "ran\n") {
+		t.Errorf("A stale script should refuse to run, got %q", output)
+	}
+	if !strings.Contains(string(output), "refusing to run a transition script") {
+		t.Errorf("Expected a clear refusal message, got %q", output)
+	}
+
+	if got := scriptMaxAge(&Options{}); got != defaultScriptMaxAge {
+		t.Errorf("Expected the default max age, got %v", got)
+	}
+	if got := scriptMaxAge(&Options{ScriptMaxAge: -1}); got != 0 {
+		t.Errorf("A negative max age should disable the check, got %v", got)
+	}
+}
+
 // Test platform support
 func TestIsSupported(t *testing.T) {
 	supported := IsSupported()
@@ -159,6 +311,24 @@ func TestGetTempDir(t *testing.T) {
 	}
 }
 
+// Test the default temp directory, which honours TMPDIR and is otherwise
+// the platform's per-user location where there is one
+func TestSystemTempDir(t *testing.T) {
+	original, had := os.LookupEnv("TMPDIR")
+	defer restoreEnv("TMPDIR", original, had)
+
+	custom := t.TempDir()
+	os.Setenv("TMPDIR", custom)
+	if got := systemTempDir(); got != custom {
+		t.Errorf("Expected TMPDIR %s, got %s", custom, got)
+	}
+
+	os.Unsetenv("TMPDIR")
+	if got := systemTempDir(); !DirectoryExists(got) {
+		t.Errorf("Expected an existing directory, got %q", got)
+	}
+}
+
 // Test script generation
 func TestGenerateScript_AllShellTypes(t *testing.T) {
 	testPath := "/tmp/test"
@@ -173,7 +343,7 @@ func TestGenerateScript_AllShellTypes(t *testing.T) {
 
 	for _, shell := range shells {
 		t.Run(filepath.Base(shell.Path), func(t *testing.T) {
-			script, err := generateScript(testPath, shell)
+			script, err := generateScript(testPath, shell, nil, nil)
 			if err != nil {
 				t.Errorf("Script generation failed for %s: %v", shell.Path, err)
 				return
@@ -206,7 +376,7 @@ func TestScriptPathSanitization_QuoteEscaping(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(filepath.Base(test.shell.Path), func(t *testing.T) {
-			script, err := generateScript(pathWithQuotes, test.shell)
+			script, err := generateScript(pathWithQuotes, test.shell, nil, nil)
 			if err != nil {
 				t.Errorf("Script generation failed: %v", err)
 				return
@@ -241,7 +411,7 @@ func TestScriptPathSanitization_PlatformSpecific(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			script, err := generateScript(test.dangerousPath, test.shell)
+			script, err := generateScript(test.dangerousPath, test.shell, nil, nil)
 			if err != nil {
 				t.Errorf("Script generation failed: %v", err)
 				return
@@ -333,6 +503,48 @@ func TestCleanupOldScriptsWithAge(t *testing.T) {
 	}
 }
 
+// Test the cleanup age and disable switch applied by each transition
+func TestStartCleanup_Options(t *testing.T) {
+	tempDir := t.TempDir()
+	script := filepath.Join(tempDir, "autocd_legacy.sh")
+
+	tests := []struct {
+		name    string
+		opts    Options
+		removed bool
+	}{
+		{"disabled", Options{DisableCleanup: true}, false},
+		{"longer_max_age", Options{CleanupMaxAge: 3 * time.Hour}, false},
+		{"default_max_age", Options{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.WriteFile(script, []byte("#!/bin/sh\n"), 0700)
+			old := time.Now().Add(-2 * time.Hour)
+			os.Chtimes(script, old, old)
+
+			opts := tt.opts
+			opts.TempDir = tempDir
+			<-startCleanup(&opts)
+
+			_, err := os.Stat(script)
+			if removed := os.IsNotExist(err); removed != tt.removed {
+				t.Errorf("Script removed=%v, expected %v", removed, tt.removed)
+			}
+		})
+	}
+}
+
+// Test that waiting for the sweep is bounded by the grace period
+func TestWaitCleanup_Deadline(t *testing.T) {
+	start := time.Now()
+	waitCleanup(make(chan struct{}), 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waitCleanup should give up after the grace period, took %s", elapsed)
+	}
+}
+
 // Test path validation edge cases
 func TestPathValidation_EdgeCases(t *testing.T) {
 	tests := []struct {
@@ -386,3 +598,179 @@ func TestSetExecutablePermissions(t *testing.T) {
 		t.Error("File is not executable after SetExecutablePermissions")
 	}
 }
+
+// Test that missing targets are only deferred under SecurityPermissive with AllowMissingTarget
+func TestExitWithDirectoryAdvanced_MissingTarget(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "not", "yet", "created")
+
+	err := ExitWithDirectoryAdvanced(missing, &Options{SecurityLevel: SecurityNormal, AllowMissingTarget: true, DisableDepthWarnings: true})
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("AllowMissingTarget should be ignored outside SecurityPermissive, got: %v", err)
+	}
+
+	validated, err := validateMissingTarget(missing + "/../created")
+	if err != nil {
+		t.Fatalf("validateMissingTarget failed: %v", err)
+	}
+	if validated != filepath.Clean(missing) {
+		t.Errorf("Expected cleaned absolute path %s, got %s", filepath.Clean(missing), validated)
+	}
+}
+
+// Test that a caller-provided shell is checked instead of detected
+func TestExitWithDirectoryUsingShell_InvalidShell(t *testing.T) {
+	err := ExitWithDirectoryUsingShell(t.TempDir(), nil)
+	if !IsShellError(err) {
+		t.Errorf("Expected a shell error for nil ShellInfo, got: %v", err)
+	}
+
+	// IsValid from the caller is not trusted
+	err = ExitWithDirectoryUsingShell(t.TempDir(), &ShellInfo{Path: "/nonexistent/shell", IsValid: true})
+	if !IsShellError(err) || !strings.Contains(err.Error(), "/nonexistent/shell") {
+		t.Errorf("Expected a shell error naming the provided shell, got: %v", err)
+	}
+}
+
+// Test the missing-target policies applied when revalidating before exec
+func TestRevalidateTarget(t *testing.T) {
+	base := t.TempDir()
+	fallback := t.TempDir()
+	vanished := filepath.Join(base, "gone", "deeper")
+
+	if dir, err := revalidateTarget(base, &Options{}); err != nil || dir != base {
+		t.Errorf("Existing target should be kept, got %q, %v", dir, err)
+	}
+
+	if _, err := revalidateTarget(vanished, &Options{}); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Default policy should abort with ErrPathNotFound, got: %v", err)
+	}
+
+	dir, err := revalidateTarget(vanished, &Options{MissingTarget: MissingTargetAncestor})
+	if err != nil || dir != base {
+		t.Errorf("Expected nearest ancestor %s, got %q, %v", base, dir, err)
+	}
+
+	dir, err = revalidateTarget(vanished, &Options{MissingTarget: MissingTargetFallbackDir, FallbackDir: fallback})
+	if err != nil || dir != fallback {
+		t.Errorf("Expected fallback %s, got %q, %v", fallback, dir, err)
+	}
+
+	if _, err := revalidateTarget(vanished, &Options{MissingTarget: MissingTargetFallbackDir}); err == nil {
+		t.Error("Fallback policy without FallbackDir should abort")
+	}
+	if _, err := revalidateTarget(vanished, &Options{MissingTarget: MissingTargetFallbackDir, FallbackDir: vanished}); err == nil {
+		t.Error("Missing FallbackDir should abort")
+	}
+}
+
+// Test accessibility follows cd semantics (search permission only)
+func TestIsDirectoryAccessible_ExecuteOnly(t *testing.T) {
+	base := t.TempDir()
+
+	execOnly := filepath.Join(base, "exec_only")
+	noSearch := filepath.Join(base, "no_search")
+	for _, dir := range []string{execOnly, noSearch} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	os.Chmod(execOnly, 0100)
+	os.Chmod(noSearch, 0600)
+	defer os.Chmod(execOnly, 0700)
+	defer os.Chmod(noSearch, 0700)
+
+	if !IsDirectoryAccessible(execOnly) {
+		t.Error("Execute-only directory should be accessible")
+	}
+	// Root may search any directory, so only check the denial for regular users
+	if os.Geteuid() != 0 && IsDirectoryAccessible(noSearch) {
+		t.Error("Directory without search permission should not be accessible")
+	}
+}
+
+// Test that OnBeforeExec can cancel a prepared transition
+func TestOnBeforeExec_Veto(t *testing.T) {
+	target := t.TempDir()
+	errUnsaved := errors.New("unsaved buffers")
+
+	var seen Transition
+	opts := &Options{
+		TempDir:              t.TempDir(),
+		SkipTTYCheck:         true,
+		DisableDepthWarnings: true,
+		DisableCleanup:       true,
+		OnBeforeExec: func(tr Transition) error {
+			seen = tr
+			return errUnsaved
+		},
+	}
+
+	err := ExitWithDirectoryAdvanced(target, opts)
+	if !errors.Is(err, errUnsaved) {
+		t.Fatalf("Expected the veto error, got: %v", err)
+	}
+	var autoErr *AutoCDError
+	if !errors.As(err, &autoErr) || autoErr.Type != ErrorVetoed {
+		t.Errorf("Expected an ErrorVetoed AutoCDError, got: %#v", err)
+	}
+
+	if seen.TargetDir != target || seen.Shell == nil || seen.ScriptPath == "" {
+		t.Errorf("Hook received an incomplete transition: %+v", seen)
+	}
+	if _, err := os.Stat(seen.ScriptPath); !os.IsNotExist(err) {
+		t.Errorf("The script should be removed after a veto, got: %v", err)
+	}
+}
+
+// Test that LoginShell and ShellArgs reach the shell in order
+func TestGenerateScript_LoginShell(t *testing.T) {
+	fake := filepath.Join(t.TempDir(), "bash")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake shell: %v", err)
+	}
+
+	shell := &ShellInfo{Path: fake, IsValid: true, Args: []string{"--norc"}}
+	opts := &Options{LoginShell: true, ShellArgs: []string{"-i", "-o", "vi"}, PlainOutput: true}
+	script, err := generateScript(t.TempDir(), shell, opts, &shellLaunch{Args: []string{"--rcfile", "/x"}})
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+	output, err := exec.Command("/bin/sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if !strings.HasSuffix(string(output), "--rcfile\n/x\n-l\n-i\n-o\nvi\n--norc\n") {
+		t.Errorf("Unexpected shell arguments:\n%s", output)
+	}
+}
+
+// Test that csh-family shells never see POSIX syntax: /bin/sh runs the
+// transition script and tcsh is only exec'd, without rc customizations
+func TestGenerateScript_CshFamily(t *testing.T) {
+	target := t.TempDir()
+	for _, name := range []string{"tcsh", "csh"} {
+		fake := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(fake, []byte("#!/bin/sh\necho \"$#:$(pwd -P)\"\n"), 0755); err != nil {
+			t.Fatalf("Failed to write fake shell: %v", err)
+		}
+
+		shell := &ShellInfo{Path: fake, IsValid: true}
+		opts := &Options{PromptPrefix: "(app) ", BackFunction: "back", TempDir: t.TempDir(), PlainOutput: true}
+		launch, err := prepareShellLaunch(shell, opts)
+		if err != nil {
+			t.Fatalf("prepareShellLaunch failed: %v", err)
+		}
+		script, err := generateScript(target, shell, opts, launch)
+		if err != nil {
+			t.Fatalf("generateScript failed: %v", err)
+		}
+		output, err := exec.Command("/bin/sh", "-c", script).Output()
+		if err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		resolved, _ := filepath.EvalSymlinks(target)
+		if !strings.HasSuffix(string(output), "0:"+resolved+"\n") {
+			t.Errorf("%s: expected no arguments and %s as working directory, got:\n%s", name, resolved, output)
+		}
+	}
+}
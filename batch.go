@@ -0,0 +1,82 @@
+package autocd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// DirectoryResult is the outcome of validating one candidate directory
+type DirectoryResult struct {
+	Path          string // Path as passed in
+	ValidatedPath string // Cleaned absolute path autocd would use ("" on error)
+	Err           error  // Validation error as returned by ValidateDirectory, or nil
+}
+
+// ValidateDirectories validates many candidate directories in one call,
+// returning one result per input path in the same order. Duplicate paths
+// are stat'ed once, and paths below a parent already found to be missing
+// are rejected without touching the filesystem, which keeps greying out
+// large candidate lists in a UI cheap.
+func ValidateDirectories(paths []string, securityLevel SecurityLevel) []DirectoryResult {
+	results := make([]DirectoryResult, len(paths))
+	checked := make(map[string]error)
+	missing := make(map[string]bool)
+
+	for i, path := range paths {
+		results[i].Path = path
+
+		target, err := resolveTarget(path)
+		if err != nil {
+			results[i].Err = newPathValidationError(path, err)
+			continue
+		}
+
+		absPath, err := filepath.Abs(target)
+		if err != nil {
+			results[i].Err = newPathValidationError(path, fmt.Errorf("invalid path: %w", err))
+			continue
+		}
+
+		statErr, seen := checked[absPath]
+		if !seen {
+			if hasMissingAncestor(absPath, missing) {
+				statErr = ErrPathNotFound
+			} else {
+				statErr = statDirectory(absPath)
+			}
+			checked[absPath] = statErr
+			if errors.Is(statErr, ErrPathNotFound) {
+				missing[absPath] = true
+			}
+		}
+		if statErr != nil {
+			results[i].Err = newPathValidationError(path, statErr)
+			continue
+		}
+
+		validatedPath, err := validateLevel(absPath, securityLevel)
+		if err != nil {
+			results[i].Err = newPathValidationError(path, err)
+			continue
+		}
+		results[i].ValidatedPath = validatedPath
+	}
+
+	return results
+}
+
+// hasMissingAncestor reports whether any parent of absPath is known not to exist
+func hasMissingAncestor(absPath string, missing map[string]bool) bool {
+	if len(missing) == 0 {
+		return false
+	}
+	for dir := filepath.Dir(absPath); ; dir = filepath.Dir(dir) {
+		if missing[dir] {
+			return true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return false
+		}
+	}
+}
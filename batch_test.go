@@ -0,0 +1,80 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test batch validation results match single-path validation
+func TestValidateDirectories(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	missing := filepath.Join(tempDir, "missing")
+
+	paths := []string{
+		tempDir,
+		file,
+		missing,
+		filepath.Join(missing, "child"),
+		tempDir + "/.",
+		"",
+	}
+
+	results := ValidateDirectories(paths, SecurityNormal)
+	if len(results) != len(paths) {
+		t.Fatalf("Expected %d results, got %d", len(paths), len(results))
+	}
+
+	for i, result := range results {
+		if result.Path != paths[i] {
+			t.Errorf("Result %d: expected path %q, got %q", i, paths[i], result.Path)
+		}
+
+		single := ValidateDirectory(paths[i], SecurityNormal)
+		if (single == nil) != (result.Err == nil) {
+			t.Errorf("%q: batch error %v, single error %v", paths[i], result.Err, single)
+			continue
+		}
+		if single != nil && single.(*AutoCDError).Type != result.Err.(*AutoCDError).Type {
+			t.Errorf("%q: batch error %v, single error %v", paths[i], result.Err, single)
+		}
+		if result.Err == nil && result.ValidatedPath == "" {
+			t.Errorf("%q: expected a validated path", paths[i])
+		}
+	}
+
+	if results[4].ValidatedPath != filepath.Clean(tempDir) {
+		t.Errorf("Expected cleaned path %s, got %s", filepath.Clean(tempDir), results[4].ValidatedPath)
+	}
+	if !errors.Is(results[3].Err, ErrPathNotFound) {
+		t.Errorf("Expected child of missing dir to be not found, got %v", results[3].Err)
+	}
+}
+
+// Test missing-ancestor detection used to skip stat calls
+func TestHasMissingAncestor(t *testing.T) {
+	missing := map[string]bool{"/a/b": true}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/a/b/c", true},
+		{"/a/b/c/d", true},
+		{"/a/b", false},
+		{"/a/bc", false},
+		{"/x", false},
+		{"/", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasMissingAncestor(tt.path, missing); got != tt.expected {
+			t.Errorf("hasMissingAncestor(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+}
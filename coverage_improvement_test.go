@@ -7,7 +7,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -103,7 +105,7 @@ func TestCreateTemporaryScript(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			scriptPath, err := createTemporaryScript(tt.content, tt.extension, tt.tempDir)
+			scriptPath, err := createTemporaryScript(tt.content, tt.extension, tt.tempDir, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createTemporaryScript() error = %v, wantErr %v", err, tt.wantErr)
@@ -446,11 +448,19 @@ func TestDetectUnixShell_EdgeCases(t *testing.T) {
 	originalShell := os.Getenv("SHELL")
 	defer os.Setenv("SHELL", originalShell)
 
-	// Test with empty SHELL
+	// Test with empty SHELL and no usable passwd entry
+	originalPasswd := passwdFile
+	passwdFile = "/non/existent/passwd"
+	defer func() { passwdFile = originalPasswd }()
+
 	os.Unsetenv("SHELL")
 	shell := detectUnixShell()
-	if shell.Path != "/bin/sh" {
-		t.Errorf("detectUnixShell with no SHELL env should default to /bin/sh, got %s", shell.Path)
+	if expected := passwdShell(); expected == "" || !fileExists(expected) {
+		if shell.Path != "/bin/sh" {
+			t.Errorf("detectUnixShell with no SHELL env should default to /bin/sh, got %s", shell.Path)
+		}
+	} else if shell.Path != expected {
+		t.Errorf("detectUnixShell with no SHELL env should use getent shell %s, got %s", expected, shell.Path)
 	}
 
 	// Test with custom SHELL; fallback to /bin/sh if invalid
@@ -461,12 +471,157 @@ func TestDetectUnixShell_EdgeCases(t *testing.T) {
 			t.Error("detectUnixShell should detect fish shell from SHELL env when present")
 		}
 	} else {
-		if shell.Path != "/bin/sh" {
-			t.Error("detectUnixShell should fallback to /bin/sh when SHELL is invalid")
+		if !shell.IsValid {
+			t.Error("detectUnixShell should fallback to a valid shell when SHELL is invalid")
 		}
 	}
 }
 
+// Test custom shell detection orders
+func TestDetectShellOrdered(t *testing.T) {
+	originalShell, had := os.LookupEnv("SHELL")
+	defer restoreEnv("SHELL", originalShell, had)
+	originalPasswd := passwdFile
+	passwdFile = "/non/existent/passwd"
+	defer func() { passwdFile = originalPasswd }()
+
+	os.Setenv("SHELL", "/bin/sh")
+
+	// Override wins when its tier comes first
+	shell := detectShellOrdered("/non/existent/shell", nil)
+	if shell.IsValid || shell.Path != "/non/existent/shell" {
+		t.Errorf("Expected the invalid override to be returned, got %+v", shell)
+	}
+
+	// Leaving out the override tier ignores the override
+	shell = detectShellOrdered("/non/existent/shell", []DetectionSource{DetectShellEnv})
+	if !shell.IsValid || shell.Path != "/bin/sh" {
+		t.Errorf("Expected SHELL to be used, got %+v", shell)
+	}
+
+	// Tiers that find nothing fall through to the next
+	os.Setenv("SHELL", "/non/existent/shell")
+	shell = detectShellOrdered("", []DetectionSource{DetectShellEnv, DetectFallback})
+	if !shell.IsValid || shell.Path != "/bin/sh" {
+		t.Errorf("Expected fallback to /bin/sh, got %+v", shell)
+	}
+
+	// Exhausting every tier yields an invalid shell
+	shell = detectShellOrdered("", []DetectionSource{DetectShellEnv})
+	if shell.IsValid {
+		t.Errorf("Expected no valid shell, got %+v", shell)
+	}
+}
+
+// Test that shells failing the interactive probe fall through the chain
+func TestDetectShellAccepted_Probe(t *testing.T) {
+	broken := filepath.Join(t.TempDir(), "broken-shell")
+	if err := os.WriteFile(broken, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake shell: %v", err)
+	}
+	t.Setenv("SHELL", broken)
+
+	probe := func(shell *ShellInfo) bool {
+		return probeInteractiveShell(shell, 5*time.Second) == nil
+	}
+
+	shell := detectShellAccepted("", []DetectionSource{DetectShellEnv, DetectFallback}, probe)
+	if !shell.IsValid || shell.Path != "/bin/sh" {
+		t.Errorf("Expected the probe to skip %s for /bin/sh, got %+v", broken, shell)
+	}
+
+	shell = detectShellAccepted("", []DetectionSource{DetectShellEnv}, probe)
+	if shell.IsValid || shell.Path != broken {
+		t.Errorf("Expected an invalid shell naming the rejected candidate, got %+v", shell)
+	}
+}
+
+// Test that the probe runs in its own session, away from the terminal
+func TestProbeInteractiveShell_NewSession(t *testing.T) {
+	if _, err := exec.LookPath("ps"); err != nil {
+		t.Skip("ps not available")
+	}
+	fake := filepath.Join(t.TempDir(), "session-shell")
+	check := "#!/bin/sh\n[ \"$(ps -o sid= -p $$ | tr -d ' ')\" = \"$$\" ]\n"
+	if err := os.WriteFile(fake, []byte(check), 0755); err != nil {
+		t.Fatalf("Failed to write fake shell: %v", err)
+	}
+	if err := probeInteractiveShell(&ShellInfo{Path: fake}, 5*time.Second); err != nil {
+		t.Errorf("Expected the probe to lead its own session: %v", err)
+	}
+}
+
+// Test parent-process shell detection through a real shell parent
+func TestParentProcessShell(t *testing.T) {
+	if os.Getenv("AUTOCD_TEST_PARENT_SHELL") != "" {
+		fmt.Println(parentProcessShell())
+		return
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Re-run this test as a child of sh; the trailing command keeps sh
+	// from exec'ing the test binary directly
+	cmd := exec.Command("sh", "-c", `"$0" -test.run '^TestParentProcessShell$'; :`, os.Args[0])
+	cmd.Env = append(os.Environ(), "AUTOCD_TEST_PARENT_SHELL=1")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Child test failed: %v", err)
+	}
+
+	detected := strings.SplitN(string(out), "\n", 2)[0]
+	if shellName(detected) == "" || !interactiveShellNames[shellName(detected)] {
+		t.Errorf("Expected a shell path from the parent process, got %q", out)
+	}
+}
+
+// Test login shell lookup from a passwd file
+func TestPasswdShell(t *testing.T) {
+	uid := strconv.Itoa(os.Getuid())
+	passwd := filepath.Join(t.TempDir(), "passwd")
+	content := "other:x:99999:99999::/home/other:/bin/false\n" +
+		"me:x:" + uid + ":" + uid + "::/home/me:/usr/local/bin/exotic-shell\n"
+	if err := os.WriteFile(passwd, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write passwd file: %v", err)
+	}
+
+	originalPasswd := passwdFile
+	passwdFile = passwd
+	defer func() { passwdFile = originalPasswd }()
+
+	if got := passwdShell(); got != "/usr/local/bin/exotic-shell" {
+		t.Errorf("passwdShell() = %q, want /usr/local/bin/exotic-shell", got)
+	}
+
+	if _, ok := passwdEntryField("broken:line", uid, passwdShellField); ok {
+		t.Error("passwdEntryField should reject malformed entries")
+	}
+}
+
+// Test that passwd entries refusing logins fall through to the next tier
+func TestPasswdShell_RefusesNologin(t *testing.T) {
+	uid := strconv.Itoa(os.Getuid())
+	for _, shell := range []string{"/usr/sbin/nologin", "/bin/false"} {
+		passwd := filepath.Join(t.TempDir(), "passwd")
+		content := "me:x:" + uid + ":" + uid + "::/home/me:" + shell + "\n"
+		if err := os.WriteFile(passwd, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write passwd file: %v", err)
+		}
+
+		originalPasswd := passwdFile
+		passwdFile = passwd
+		if got := passwdShell(); got != "" {
+			t.Errorf("passwdShell() = %q, want no shell for %s", got, shell)
+		}
+		detected := detectShellOrdered("", []DetectionSource{DetectPasswd, DetectFallback})
+		if detected.Path != "/bin/sh" {
+			t.Errorf("Expected the fallback shell instead of %s, got %q", shell, detected.Path)
+		}
+		passwdFile = originalPasswd
+	}
+}
+
 // Test IsRecoverable for all error types
 func TestIsRecoverable_AllErrorTypes(t *testing.T) {
 	tests := []struct {
@@ -493,3 +648,133 @@ func TestIsRecoverable_AllErrorTypes(t *testing.T) {
 		})
 	}
 }
+
+// Test /etc/shells style allow-list checks for shell overrides
+func TestCheckAllowedShell(t *testing.T) {
+	tempDir := t.TempDir()
+	shellsFile := filepath.Join(tempDir, "shells")
+	content := "# valid login shells\n/bin/sh\n\n/usr/local/bin/custom-shell\n"
+	if err := os.WriteFile(shellsFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write shells file: %v", err)
+	}
+
+	if err := checkAllowedShell("/bin/sh", shellsFile); err != nil {
+		t.Errorf("Listed shell should be allowed: %v", err)
+	}
+
+	err := checkAllowedShell("/usr/bin/python3", shellsFile)
+	if !errors.Is(err, ErrShellNotAllowed) || !errors.Is(err, ErrSecurityViolation) {
+		t.Errorf("Unlisted shell should be rejected with ErrShellNotAllowed, got: %v", err)
+	}
+
+	err = checkAllowedShell("/bin/sh", filepath.Join(tempDir, "missing"))
+	if !errors.Is(err, ErrShellNotAllowed) {
+		t.Errorf("Missing shells file should reject the shell, got: %v", err)
+	}
+}
+
+// Test command-line splitting for shell overrides
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"bash --noprofile -i", []string{"bash", "--noprofile", "-i"}, false},
+		{"  zsh   -l ", []string{"zsh", "-l"}, false},
+		{`fish -C 'echo "hi there"'`, []string{"fish", "-C", `echo "hi there"`}, false},
+		{`/opt/my\ shell "-x y"`, []string{"/opt/my shell", "-x y"}, false},
+		{`bash ''`, []string{"bash", ""}, false},
+		{`bash 'unterminated`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := splitCommandLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommandLine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || len(got) != len(tt.want) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+// Test shell overrides given as a full command line
+func TestValidateShellOverride_CommandLine(t *testing.T) {
+	shell := validateShellOverride("sh -i")
+	if !shell.IsValid {
+		t.Fatalf("Expected valid shell for command line override, got %+v", shell)
+	}
+	if len(shell.Args) != 1 || shell.Args[0] != "-i" {
+		t.Errorf("Expected args [-i], got %q", shell.Args)
+	}
+
+	script, err := generateScript("/tmp/test", shell, nil, nil)
+	if err != nil {
+		t.Fatalf("Script generation failed: %v", err)
+	}
+	if !strings.Contains(script, `exec "$SHELL_PATH" '-i'`) {
+		t.Errorf("Script should pass override arguments to the shell, got:\n%s", script)
+	}
+
+	if validateShellOverride("sh 'broken").IsValid {
+		t.Error("Malformed command line should produce an invalid shell")
+	}
+}
+
+// Test platform-aware path and component length limits
+func TestCheckPathLength(t *testing.T) {
+	if err := checkPathLength("/tmp/ok"); err != nil {
+		t.Errorf("Short path should pass: %v", err)
+	}
+
+	longComponent := "/tmp/" + strings.Repeat("a", maxNameLength()+1)
+	if err := checkPathLength(longComponent); !errors.Is(err, ErrPathTooLong) {
+		t.Errorf("Expected ErrPathTooLong for long component, got: %v", err)
+	}
+
+	longPath := strings.Repeat("/abc", maxPathLength()/4+1)
+	if err := checkPathLength(longPath); !errors.Is(err, ErrPathTooLong) {
+		t.Errorf("Expected ErrPathTooLong for long path, got: %v", err)
+	}
+
+	autoErr := newPathValidationError(longPath, fmt.Errorf("%w: test", ErrPathTooLong))
+	if autoErr.Type != ErrorPathTooLong || !IsPathError(autoErr) {
+		t.Errorf("Expected ErrorPathTooLong path error, got type %v", autoErr.Type)
+	}
+}
+
+// Test errno explanations for failed exec calls
+func TestExplainExecError(t *testing.T) {
+	for _, errno := range []syscall.Errno{syscall.ENOENT, syscall.EACCES, syscall.ENOEXEC, syscall.E2BIG} {
+		t.Run(errno.Error(), func(t *testing.T) {
+			err := explainExecError("/bin/sh", errno)
+
+			var execErr *ExecError
+			if !errors.As(err, &execErr) {
+				t.Fatalf("Expected *ExecError, got %T", err)
+			}
+			if !errors.Is(err, errno) {
+				t.Error("ExecError should unwrap to its errno")
+			}
+			if execErr.Explanation == "" || !strings.Contains(err.Error(), "/bin/sh") {
+				t.Errorf("Expected explained error mentioning the executable, got: %v", err)
+			}
+			if strings.Contains(execErr.Explanation, "script") {
+				t.Errorf("/bin/sh failures should not be blamed on the script: %v", err)
+			}
+		})
+	}
+
+	plain := errors.New("not an errno")
+	if explainExecError("/bin/sh", plain) != plain {
+		t.Error("Non-errno errors should be returned unchanged")
+	}
+
+	wrapped := newScriptExecutionError(explainExecError("/bin/sh", syscall.EACCES))
+	if !strings.Contains(wrapped.Error(), "noexec") {
+		t.Errorf("AutoCDError message should carry the explanation, got: %v", wrapped)
+	}
+}
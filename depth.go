@@ -0,0 +1,177 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// maxAncestryWalk bounds the process-tree walk against cycles or
+// pathological trees
+const maxAncestryWalk = 128
+
+// interactiveShellNames are process names counted as a nesting level when
+// walking the process ancestry: every shell in shellTypesByName except
+// cmd.exe, which never appears in a Unix process tree
+var interactiveShellNames = func() map[string]bool {
+	names := make(map[string]bool, len(shellTypesByName))
+	for name, shellType := range shellTypesByName {
+		if shellType != ShellCmd {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// resetDepthEnvironment returns the SHLVL making the spawned shell report a
+// depth of 1 when Options.ResetShellDepth is set
+func resetDepthEnvironment(shell *ShellInfo, opts *Options) []envVar {
+	if !opts.ResetShellDepth {
+		return nil
+	}
+	if shell.Type().Capabilities().IncrementsSHLVL {
+		return []envVar{{Name: "SHLVL", Value: "0"}}
+	}
+	return []envVar{{Name: "SHLVL", Value: "1"}}
+}
+
+// IDE terminals start their shells several levels deep (wrapper scripts,
+// shell integration), so the default tip threshold is raised there
+const (
+	depthWarningThreshold    = 15
+	ideDepthWarningAllowance = 5
+)
+
+// defaultDepthWarningThreshold returns the depth tip threshold used when
+// Options.DepthWarningThreshold is unset
+func defaultDepthWarningThreshold() int {
+	if ideTerminal() != "" {
+		return depthWarningThreshold + ideDepthWarningAllowance
+	}
+	return depthWarningThreshold
+}
+
+// depthTipEnv carries, through the shells of a terminal session, how many
+// transitions ago the depth tip was shown ("" = not shown yet)
+const depthTipEnv = "AUTOCD_DEPTH_TIP"
+
+// depthTipDue applies the rate limit to the depth tip given the inherited
+// marker
+func depthTipDue(marker string, opts *Options) bool {
+	if marker == "" || (opts.DepthWarningInterval <= 0 && !opts.DepthWarningOnce) {
+		return true
+	}
+	if opts.DepthWarningOnce {
+		return false
+	}
+	since, err := strconv.Atoi(marker)
+	return err != nil || since+1 >= opts.DepthWarningInterval
+}
+
+// nextDepthTipMarker returns the marker for the spawned shell
+func nextDepthTipMarker(marker string, shown bool) string {
+	if shown {
+		return "0"
+	}
+	if marker == "" {
+		return ""
+	}
+	since, err := strconv.Atoi(marker)
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(since + 1)
+}
+
+// ShellDepth returns the current shell nesting depth. It trusts SHLVL when
+// it holds a positive number; otherwise it walks the process ancestry,
+// counting interactive shells and shells spawned by autocd. Returns 0 when
+// the depth cannot be determined.
+func ShellDepth() int {
+	if depth, ok := shlvlDepth(); ok {
+		return depth
+	}
+	return processTreeDepth(os.Getppid())
+}
+
+// shlvlDepth parses SHLVL, rejecting missing, malformed and non-positive values
+func shlvlDepth() (int, bool) {
+	depth, err := strconv.Atoi(os.Getenv("SHLVL"))
+	if err != nil || depth <= 0 {
+		return 0, false
+	}
+	return depth, true
+}
+
+// processTreeDepth counts nesting levels from pid up to init
+func processTreeDepth(pid int) int {
+	depth := 0
+	for i := 0; i < maxAncestryWalk && pid > 1; i++ {
+		parent, name, err := processParent(pid)
+		if err != nil {
+			break
+		}
+		if interactiveShellNames[shellName(name)] || isAutocdShell(pid) {
+			depth++
+		}
+		pid = parent
+	}
+	return depth
+}
+
+// depthLimit returns the depth at which Options.DepthAction applies
+func depthLimit(opts *Options) int {
+	if opts.DepthLimit > 0 {
+		return opts.DepthLimit
+	}
+	return opts.DepthWarningThreshold
+}
+
+// depthLimitReached reports whether depth triggers a block or collapse
+func depthLimitReached(depth int, opts *Options) bool {
+	return opts.DepthAction != DepthActionWarn && depth > 0 && depth >= depthLimit(opts)
+}
+
+// DepthStateFile returns the file DepthActionCollapse writes the target
+// directory to: $XDG_STATE_HOME/autocd/dir, or ~/.local/state/autocd/dir.
+// A wrapper function or prompt hook in the outermost shell can cd there
+// and remove it.
+func DepthStateFile() string {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if !filepath.IsAbs(stateHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "autocd", "dir")
+}
+
+// enforceDepthLimit applies Options.DepthAction once the target is
+// validated: it returns ErrDepthLimit (block) or writes the state file and
+// exits (collapse) when the shell depth has reached the limit
+func enforceDepthLimit(validatedPath string, opts *Options) error {
+	depth := ShellDepth()
+	if !depthLimitReached(depth, opts) {
+		return nil
+	}
+	if opts.DepthAction == DepthActionBlock {
+		return newDepthError(depth, depthLimit(opts))
+	}
+
+	file := DepthStateFile()
+	if file == "" {
+		return newDepthError(depth, depthLimit(opts))
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return newScriptCreationError(fmt.Errorf("failed to create state directory: %w", err))
+	}
+	if err := writeLastDirFile(validatedPath, file); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "autocd: %d nested shells; %s saved to %s for the outer shell\n", depth, validatedPath, file)
+	exitProcess(opts.AppExitStatus)
+	return nil
+}
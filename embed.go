@@ -0,0 +1,93 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// BuildCommand prepares the transition into path like
+// ExitWithDirectoryAdvanced (validation, shell detection, script) but
+// returns a ready-to-run command instead of exec'ing it, for frameworks
+// that manage the process lifecycle themselves, such as bubbletea's
+// ExecProcess. The command runs the transition script with the terminal's
+// standard streams. OnBeforeExec, the clipboard and tmux exports, the
+// result report and the audit record run as for an exec, before
+// BuildCommand returns. Wrapper protocols (CDFileEnv, OutCmdFile,
+// LastDirFile) and the file manager fallback do not apply.
+//
+// cleanup removes the script and other temporary files; call it once the
+// command has exited, or when it is not run at all.
+func BuildCommand(path string, opts *Options) (*exec.Cmd, func(), error) {
+	return buildCommand(path, withDefaults(opts), nil)
+}
+
+// buildCommand implements BuildCommand; presetShell, when non-nil, replaces
+// shell detection
+func buildCommand(path string, opts *Options, presetShell *ShellInfo) (*exec.Cmd, func(), error) {
+	t, err := prepareTransition(path, opts, presetShell, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd, closeScript, err := scriptCommand(t.ScriptPath)
+	if err != nil {
+		t.discard()
+		return nil, nil, newScriptCreationError(err)
+	}
+	cmd.Env = execEnvironment(opts)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// A target allowed to be missing is left to the script's cd
+	if DirectoryExists(t.TargetDir) {
+		cmd.Dir = t.TargetDir
+	}
+
+	if err := t.commit(opts); err != nil {
+		closeScript()
+		return nil, nil, err
+	}
+	return cmd, func() { closeScript(); t.discard() }, nil
+}
+
+// scriptCommand returns a command running scriptPath with /bin/sh. Our
+// /dev/fd descriptors are close-on-exec, so such a script is reopened and
+// passed to the child as descriptor 3 through ExtraFiles. closeScript
+// closes the reopened copy.
+func scriptCommand(scriptPath string) (*exec.Cmd, func(), error) {
+	if !strings.HasPrefix(scriptPath, "/dev/fd/") {
+		return exec.Command("/bin/sh", scriptPath), func() {}, nil
+	}
+	script, err := os.Open(scriptPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reopen script descriptor: %w", err)
+	}
+	cmd := exec.Command("/bin/sh", "/dev/fd/3")
+	cmd.ExtraFiles = []*os.File{script}
+	return cmd, func() { script.Close() }, nil
+}
+
+// GenerateTransitionScript returns the script a transition into path would
+// execute, and the shell it would start, without replacing the process.
+// Everything short of exec runs as in ExitWithDirectoryAdvanced except the
+// terminal check, depth tip and wrapper protocols; temporary files are
+// removed before returning, so paths they appear under in the script no
+// longer exist.
+func GenerateTransitionScript(path string, opts *Options) (string, *ShellInfo, error) {
+	dryRun := *withDefaults(opts)
+	dryRun.SkipTTYCheck = true
+	dryRun.DisableDepthWarnings = true
+
+	t, err := prepareTransition(path, &dryRun, nil, false)
+	if err != nil {
+		return "", nil, err
+	}
+	defer t.discard()
+
+	script, err := os.ReadFile(t.ScriptPath)
+	if err != nil {
+		return "", nil, newScriptCreationError(err)
+	}
+	return string(script), t.Shell, nil
+}
@@ -0,0 +1,116 @@
+package autocd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test that BuildCommand prepares a runnable transition without exec'ing
+func TestBuildCommand(t *testing.T) {
+	if !fileExists("/usr/bin/env") {
+		t.Skip("/usr/bin/env not available")
+	}
+	target := t.TempDir()
+	scripts := t.TempDir()
+
+	cmd, cleanup, err := BuildCommand(target, &Options{
+		Shell:                "/usr/bin/env",
+		TempDir:              scripts,
+		SkipTTYCheck:         true,
+		DisableCleanup:       true,
+		DisableDepthWarnings: true,
+	})
+	if err != nil {
+		t.Fatalf("BuildCommand failed: %v", err)
+	}
+	if cmd.Dir != target || len(cmd.Args) != 2 || filepath.Dir(cmd.Args[1]) != scripts {
+		t.Errorf("Unexpected command: dir=%s args=%v", cmd.Dir, cmd.Args)
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Running the command failed: %v", err)
+	}
+	if !strings.Contains(string(output), "AUTOCD_TARGET_DIR="+target+"\n") {
+		t.Errorf("Expected the inherited environment from the shell, got:\n%s", output)
+	}
+
+	cleanup()
+	if _, err := os.Stat(cmd.Args[1]); !os.IsNotExist(err) {
+		t.Error("cleanup should remove the script")
+	}
+
+	if _, _, err := BuildCommand(filepath.Join(target, "missing"), &Options{SkipTTYCheck: true}); !IsPathError(err) {
+		t.Errorf("Expected a path error for a missing target, got: %v", err)
+	}
+}
+
+// Test inspecting the transition script without exec'ing it
+func TestGenerateTransitionScript(t *testing.T) {
+	target := t.TempDir()
+	scripts := t.TempDir()
+
+	script, shell, err := GenerateTransitionScript(target, &Options{Shell: "/bin/sh", TempDir: scripts, DisableCleanup: true})
+	if err != nil {
+		t.Fatalf("GenerateTransitionScript failed: %v", err)
+	}
+	if shell == nil || shell.Path != "/bin/sh" {
+		t.Errorf("Unexpected shell: %+v", shell)
+	}
+	if !strings.Contains(script, "TARGET_DIR='"+target+"'") || !strings.Contains(script, "exec ") {
+		t.Errorf("Unexpected script:\n%s", script)
+	}
+	if entries, _ := os.ReadDir(scripts); len(entries) != 0 {
+		t.Errorf("Temporary files should be removed, found %d", len(entries))
+	}
+
+	if _, _, err := GenerateTransitionScript(filepath.Join(target, "missing"), nil); !IsPathError(err) {
+		t.Errorf("Expected a path error, got: %v", err)
+	}
+}
+
+// Test that BuildCommand and the spawn strategy run the pre-exec steps:
+// the veto and the result report
+func TestBuildCommand_PreExecSteps(t *testing.T) {
+	target := t.TempDir()
+	var result bytes.Buffer
+	_, cleanup, err := BuildCommand(target, &Options{
+		TempDir:              t.TempDir(),
+		SkipTTYCheck:         true,
+		DisableCleanup:       true,
+		DisableDepthWarnings: true,
+		ResultWriter:         &result,
+	})
+	if err != nil {
+		t.Fatalf("BuildCommand failed: %v", err)
+	}
+	defer cleanup()
+	if !strings.Contains(result.String(), `"target_dir":"`+target+`"`) {
+		t.Errorf("Expected a result line for the command, got: %q", result.String())
+	}
+
+	errUnsaved := errors.New("unsaved buffers")
+	var scriptPath string
+	err = spawnTransition(target, &Options{
+		TempDir:              t.TempDir(),
+		SkipTTYCheck:         true,
+		DisableCleanup:       true,
+		DisableDepthWarnings: true,
+		OnBeforeExec: func(tr Transition) error {
+			scriptPath = tr.ScriptPath
+			return errUnsaved
+		},
+	}, nil)
+	var autoErr *AutoCDError
+	if !errors.Is(err, errUnsaved) || !errors.As(err, &autoErr) || autoErr.Type != ErrorVetoed {
+		t.Fatalf("Expected the veto to cancel the spawn, got: %v", err)
+	}
+	if _, err := os.Stat(scriptPath); !os.IsNotExist(err) {
+		t.Errorf("The script should be removed after a veto, got: %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package autocd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envVar is a single variable exported into the inherited shell
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// scriptEnvironment lists the variables the transition script exports
+// before handing over to the user's shell
+func scriptEnvironment(targetDir string, opts *Options) []envVar {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	vars := []envVar{
+		{Name: "AUTOCD_EXIT_STATUS", Value: strconv.Itoa(opts.AppExitStatus)},
+		{Name: "AUTOCD_APP", Value: appName(opts)},
+		{Name: "AUTOCD_APP_PID", Value: strconv.Itoa(os.Getpid())},
+		{Name: "AUTOCD_TARGET_DIR", Value: targetDir},
+	}
+
+	// Record where the application was started from (best effort), and
+	// add it to the trail of directories the session came through
+	if sourceDir, err := os.Getwd(); err == nil {
+		vars = append(vars,
+			envVar{Name: "AUTOCD_SOURCE_DIR", Value: sourceDir},
+			envVar{Name: TrailEnv, Value: appendTrail(os.Getenv(TrailEnv), sourceDir)},
+		)
+		// "cd -" in the new shell returns to where the application started
+		if !opts.DisableOldPwd {
+			vars = append(vars, envVar{Name: "OLDPWD", Value: sourceDir})
+		}
+	}
+
+	// Mark the prompt of the spawned shell. Shells that honour an inherited
+	// PS1 (sh, dash, ksh) pick this up directly; AUTOCD_PROMPT_PREFIX lets
+	// users' rc files reapply the marker after setting their own prompt.
+	if opts.PromptPrefix != "" {
+		vars = append(vars,
+			envVar{Name: "AUTOCD_PROMPT_PREFIX", Value: opts.PromptPrefix},
+			envVar{Name: "PS1", Value: opts.PromptPrefix + basePrompt()},
+		)
+	}
+
+	// Let "cd project" resolve against the directories the app knows about
+	if cdpath := cdPathValue(opts.CDPath); cdpath != "" {
+		vars = append(vars, envVar{Name: "CDPATH", Value: cdpath})
+	}
+
+	return vars
+}
+
+// cdPathValue joins dirs ahead of the inherited CDPATH, dropping duplicates.
+// Without an inherited CDPATH the current directory is searched first, so
+// plain relative cd keeps its usual meaning.
+func cdPathValue(dirs []string) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+
+	inherited := os.Getenv("CDPATH")
+	entries := []string{"."}
+	if inherited != "" {
+		entries = nil
+	}
+	entries = append(entries, dirs...)
+	if inherited != "" {
+		entries = append(entries, filepath.SplitList(inherited)...)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	kept := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.ContainsRune(entry, filepath.ListSeparator) || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		kept = append(kept, entry)
+	}
+	return strings.Join(kept, string(filepath.ListSeparator))
+}
+
+// TrailEnv names the variable holding the directories a terminal session
+// transitioned from, oldest first
+const TrailEnv = "AUTOCD_TRAIL"
+
+// maxTrailEntries bounds the trail so long sessions can't bloat the
+// environment towards E2BIG
+const maxTrailEntries = 50
+
+// trailEscaper protects the separator inside trail entries
+var trailEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// appendTrail adds dir to an encoded trail, skipping an immediate repeat
+// and dropping the oldest entries beyond maxTrailEntries
+func appendTrail(trail, dir string) string {
+	entries := ParseTrail(trail)
+	if len(entries) == 0 || entries[len(entries)-1] != dir {
+		entries = append(entries, dir)
+	}
+	if len(entries) > maxTrailEntries {
+		entries = entries[len(entries)-maxTrailEntries:]
+	}
+
+	encoded := make([]string, len(entries))
+	for i, entry := range entries {
+		encoded[i] = trailEscaper.Replace(entry)
+	}
+	return strings.Join(encoded, ":")
+}
+
+// ParseTrail splits an AUTOCD_TRAIL value into directories, oldest first.
+// Entries are colon-separated, with "%" and ":" inside a directory
+// written as "%25" and "%3A".
+func ParseTrail(trail string) []string {
+	if trail == "" {
+		return nil
+	}
+	parts := strings.Split(trail, ":")
+	entries := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if decoded, err := url.PathUnescape(part); err == nil {
+			part = decoded
+		}
+		entries = append(entries, part)
+	}
+	return entries
+}
+
+// Trail returns the directories the current terminal session transitioned
+// from, oldest first, as recorded in AUTOCD_TRAIL
+func Trail() []string {
+	return ParseTrail(os.Getenv(TrailEnv))
+}
+
+// shellEnvironment keeps SHELL in line with an overridden shell, so that
+// programs started from the inherited shell (tmux, editors) launch the same
+// one instead of the stale SHELL the application was started with. Minimal
+// environments (cron, containers) without SHELL get the detected shell.
+func shellEnvironment(shell *ShellInfo, opts *Options) []envVar {
+	if !shell.IsValid {
+		return nil
+	}
+	if os.Getenv("SHELL") == "" || (opts.Shell != "" && opts.ExportShell) {
+		return []envVar{{Name: "SHELL", Value: shell.Path}}
+	}
+	return nil
+}
+
+// homeEnvironment supplies HOME from the passwd entry when the application
+// runs without one, so the shell and its rc files don't resolve ~ and
+// $HOME/.bashrc against the filesystem root
+func homeEnvironment() []envVar {
+	if os.Getenv("HOME") != "" {
+		return nil
+	}
+	if home := passwdHomeFor(os.Getuid()); home != "" {
+		return []envVar{{Name: "HOME", Value: home}}
+	}
+	return nil
+}
+
+// basePrompt returns the inherited PS1 or the POSIX default prompt
+func basePrompt() string {
+	if ps1 := os.Getenv("PS1"); ps1 != "" {
+		return ps1
+	}
+	if os.Geteuid() == 0 {
+		return "# "
+	}
+	return "$ "
+}
+
+// appName returns the name of the running application: Options.AppName,
+// or the executable name
+func appName(opts *Options) string {
+	if opts != nil && opts.AppName != "" {
+		return opts.AppName
+	}
+	if len(os.Args) == 0 || os.Args[0] == "" {
+		return "autocd"
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// cleanEnvironmentAllowlist is always kept when CleanEnvironment is set
+var cleanEnvironmentAllowlist = []string{"TERM", "HOME", "PATH", "LANG"}
+
+// execEnvironment returns the environment handed to the transition script:
+// scrubbed by CleanEnvironment, without the UnsetEnv variables and with
+// ExtraEnv added. ExtraEnv travels through exec rather than the script, so
+// secrets such as API tokens are never written to disk.
+func execEnvironment(opts *Options) []string {
+	env := os.Environ()
+	if !opts.CleanEnvironment && len(opts.UnsetEnv) == 0 && len(opts.ExtraEnv) == 0 {
+		return env
+	}
+
+	allowlist := append(append([]string{}, cleanEnvironmentAllowlist...), opts.EnvAllowlist...)
+	kept := make([]string, 0, len(env))
+	for _, entry := range env {
+		name := entry
+		if i := strings.IndexByte(entry, '='); i >= 0 {
+			name = entry[:i]
+		}
+		if opts.CleanEnvironment && !envNameAllowed(name, allowlist) {
+			continue
+		}
+		if envNameAllowed(name, opts.UnsetEnv) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return applyExports(kept, extraEnvironment(opts))
+}
+
+// validEnvName matches variable names the script can export safely
+var validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkExtraEnv rejects ExtraEnv entries that are not plain variables
+func checkExtraEnv(opts *Options) error {
+	for name, value := range opts.ExtraEnv {
+		if !validEnvName.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if strings.IndexByte(value, 0) >= 0 {
+			return fmt.Errorf("environment variable %s contains a NUL byte", name)
+		}
+	}
+	return nil
+}
+
+// extraEnvironment lists Options.ExtraEnv in a stable order
+func extraEnvironment(opts *Options) []envVar {
+	names := make([]string, 0, len(opts.ExtraEnv))
+	for name := range opts.ExtraEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vars := make([]envVar, len(names))
+	for i, name := range names {
+		vars[i] = envVar{Name: name, Value: opts.ExtraEnv[name]}
+	}
+	return vars
+}
+
+// envNameAllowed matches a variable name against exact names and
+// trailing-wildcard prefixes such as "LC_*"
+func envNameAllowed(name string, allowlist []string) bool {
+	for _, allowed := range allowlist {
+		if prefix := strings.TrimSuffix(allowed, "*"); prefix != allowed {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// applyExports returns env with vars set, replacing earlier definitions
+// as the script's export statements would
+func applyExports(env []string, vars []envVar) []string {
+	exported := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		exported[v.Name] = true
+	}
+
+	merged := make([]string, 0, len(env)+len(vars))
+	for _, entry := range env {
+		name := entry
+		if i := strings.IndexByte(entry, '='); i >= 0 {
+			name = entry[:i]
+		}
+		if !exported[name] {
+			merged = append(merged, entry)
+		}
+	}
+
+	// Later exports win, as in the script
+	index := make(map[string]int, len(vars))
+	for _, v := range vars {
+		entry := v.Name + "=" + v.Value
+		if i, ok := index[v.Name]; ok {
+			merged[i] = entry
+			continue
+		}
+		index[v.Name] = len(merged)
+		merged = append(merged, entry)
+	}
+	return merged
+}
+
+// renderExports formats variables as POSIX export statements
+func renderExports(vars []envVar) string {
+	var b strings.Builder
+	for _, v := range vars {
+		b.WriteString("export ")
+		b.WriteString(v.Name)
+		b.WriteString("='")
+		b.WriteString(sanitizePathForShell(v.Value))
+		b.WriteString("'\n")
+	}
+	return b.String()
+}
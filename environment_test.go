@@ -0,0 +1,391 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// runTransitionScript executes a generated script whose shell is /usr/bin/env,
+// returning the environment the inherited shell would have received
+func runTransitionScript(t *testing.T, targetDir string, opts *Options) string {
+	t.Helper()
+
+	if !fileExists("/usr/bin/env") {
+		t.Skip("/usr/bin/env not available")
+	}
+
+	shell := &ShellInfo{Path: "/usr/bin/env", IsValid: true}
+	script, err := generateScript(targetDir, shell, opts, nil)
+	if err != nil {
+		t.Fatalf("Script generation failed: %v", err)
+	}
+
+	scriptPath := filepath.Join(t.TempDir(), "transition.sh")
+	if err := os.WriteFile(scriptPath, []byte(script), 0700); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	output, err := exec.Command("/bin/sh", scriptPath).Output()
+	if err != nil {
+		t.Fatalf("Script execution failed: %v", err)
+	}
+	return string(output)
+}
+
+// Test that the application's exit status reaches the inherited shell
+func TestScriptEnvironment_ExitStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *Options
+		expected string
+	}{
+		{"nil_options", nil, "AUTOCD_EXIT_STATUS=0"},
+		{"clean_exit", &Options{}, "AUTOCD_EXIT_STATUS=0"},
+		{"error_exit", &Options{AppExitStatus: 3}, "AUTOCD_EXIT_STATUS=3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := runTransitionScript(t, t.TempDir(), tt.opts)
+			if !strings.Contains(output, tt.expected+"\n") {
+				t.Errorf("Expected %q in inherited environment, got:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
+
+// Test that exported values are single-quote escaped
+func TestRenderExports_Quoting(t *testing.T) {
+	exports := renderExports([]envVar{{Name: "AUTOCD_TEST", Value: "it's $(whoami)"}})
+	expected := `export AUTOCD_TEST='it'"'"'s $(whoami)'` + "\n"
+	if exports != expected {
+		t.Errorf("renderExports() = %q, want %q", exports, expected)
+	}
+}
+
+// Test that originating-app metadata and the target reach the inherited shell
+func TestScriptEnvironment_AppMetadata(t *testing.T) {
+	targetDir := t.TempDir()
+	output := runTransitionScript(t, targetDir, nil)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	expected := []string{
+		"AUTOCD_APP=" + filepath.Base(os.Args[0]),
+		"AUTOCD_APP_PID=" + strconv.Itoa(os.Getpid()),
+		"AUTOCD_SOURCE_DIR=" + cwd,
+		"AUTOCD_TARGET_DIR=" + targetDir,
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want+"\n") {
+			t.Errorf("Expected %q in inherited environment", want)
+		}
+	}
+}
+
+// Test that "cd -" in the inherited shell returns to the launch directory
+func TestScriptEnvironment_OldPwd(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	output := runTransitionScript(t, t.TempDir(), nil)
+	if !strings.Contains(output, "\nOLDPWD="+cwd+"\n") {
+		t.Errorf("Expected OLDPWD=%s in inherited environment", cwd)
+	}
+
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+	script, _ := generateScript(t.TempDir(), shell, &Options{DisableOldPwd: true}, nil)
+	if strings.Contains(script, "export OLDPWD=") {
+		t.Error("OLDPWD should not be exported when disabled")
+	}
+}
+
+// Test that Options.AppName labels messages, the environment and file names
+func TestScriptEnvironment_AppName(t *testing.T) {
+	targetDir := t.TempDir()
+	output := runTransitionScript(t, targetDir, &Options{AppName: `my%app's\`, PlainOutput: true})
+
+	if !strings.HasPrefix(output, `my%app's\: Directory changed to: `+targetDir+"\n") {
+		t.Errorf("Expected a labelled banner, got:\n%s", output)
+	}
+	if !strings.Contains(output, "AUTOCD_APP=my%app's\\\n") {
+		t.Error("Expected AUTOCD_APP to carry the application name")
+	}
+
+	if pattern := artifactPattern("my app", ".sh"); !strings.HasSuffix(pattern, "_my_app-*.sh") {
+		t.Errorf("Expected the application in file names, got %q", pattern)
+	}
+	if pid, _, ok := scriptNameInfo(strings.Replace(artifactPattern("my_app", ".sh"), "*", "123", 1)); !ok || pid != os.Getpid() {
+		t.Error("Tagged file names should still carry the PID and creation time")
+	}
+}
+
+// Test that the prompt prefix is exported only when configured
+func TestScriptEnvironment_PromptPrefix(t *testing.T) {
+	originalPS1, hadPS1 := os.LookupEnv("PS1")
+	os.Setenv("PS1", "> ")
+	defer func() {
+		if hadPS1 {
+			os.Setenv("PS1", originalPS1)
+		} else {
+			os.Unsetenv("PS1")
+		}
+	}()
+
+	output := runTransitionScript(t, t.TempDir(), nil)
+	if strings.Contains(output, "AUTOCD_PROMPT_PREFIX=") {
+		t.Error("AUTOCD_PROMPT_PREFIX should not be exported by default")
+	}
+
+	output = runTransitionScript(t, t.TempDir(), &Options{PromptPrefix: "(myapp) "})
+	for _, want := range []string{"AUTOCD_PROMPT_PREFIX=(myapp) ", "PS1=(myapp) > "} {
+		if !strings.Contains(output, want+"\n") {
+			t.Errorf("Expected %q in inherited environment", want)
+		}
+	}
+}
+
+// Test CDPATH seeding ahead of an inherited CDPATH
+func TestCDPathValue(t *testing.T) {
+	original, had := os.LookupEnv("CDPATH")
+	defer func() {
+		if had {
+			os.Setenv("CDPATH", original)
+		} else {
+			os.Unsetenv("CDPATH")
+		}
+	}()
+
+	os.Unsetenv("CDPATH")
+	if got := cdPathValue(nil); got != "" {
+		t.Errorf("Expected no CDPATH without directories, got %q", got)
+	}
+	if got := cdPathValue([]string{"/work", "/src", "/work", "/bad:dir"}); got != ".:/work:/src" {
+		t.Errorf("Unexpected CDPATH without an inherited one: %q", got)
+	}
+
+	os.Setenv("CDPATH", ".:/src:/home/user")
+	if got := cdPathValue([]string{"/work", "/src"}); got != "/work:/src:.:/home/user" {
+		t.Errorf("Unexpected CDPATH with an inherited one: %q", got)
+	}
+
+	output := runTransitionScript(t, t.TempDir(), &Options{CDPath: []string{"/work"}})
+	if !strings.Contains(output, "CDPATH=/work:.:/src:/home/user\n") {
+		t.Error("Expected CDPATH in inherited environment")
+	}
+}
+
+// Test the breadcrumb trail of directories transitioned from
+func TestTrail(t *testing.T) {
+	trail := appendTrail("", "/home/user")
+	trail = appendTrail(trail, "/srv/a:b 100%")
+	trail = appendTrail(trail, "/srv/a:b 100%")
+	if trail != "/home/user:/srv/a%3Ab 100%25" {
+		t.Errorf("Unexpected trail encoding: %q", trail)
+	}
+	if got := ParseTrail(trail); strings.Join(got, "|") != "/home/user|/srv/a:b 100%" {
+		t.Errorf("Unexpected parsed trail: %v", got)
+	}
+
+	for i := 0; i < maxTrailEntries+5; i++ {
+		trail = appendTrail(trail, "/d"+strconv.Itoa(i))
+	}
+	entries := ParseTrail(trail)
+	if len(entries) != maxTrailEntries || entries[len(entries)-1] != "/d"+strconv.Itoa(maxTrailEntries+4) {
+		t.Errorf("Expected the newest %d entries, got %d ending in %s", maxTrailEntries, len(entries), entries[len(entries)-1])
+	}
+
+	original, had := os.LookupEnv(TrailEnv)
+	defer restoreEnv(TrailEnv, original, had)
+	os.Setenv(TrailEnv, "/start")
+	cwd, _ := os.Getwd()
+	output := runTransitionScript(t, t.TempDir(), nil)
+	if !strings.Contains(output, TrailEnv+"=/start:"+strings.ReplaceAll(cwd, ":", "%3A")+"\n") {
+		t.Errorf("Expected the source directory appended to the inherited trail")
+	}
+}
+
+// Test that SHELL follows the overriding shell only when requested
+func TestShellEnvironment_ExportShell(t *testing.T) {
+	originalShell, had := os.LookupEnv("SHELL")
+	os.Setenv("SHELL", "/stale/shell")
+	defer restoreEnv("SHELL", originalShell, had)
+
+	output := runTransitionScript(t, t.TempDir(), &Options{Shell: "/usr/bin/env"})
+	if !strings.Contains(output, "SHELL=/stale/shell\n") {
+		t.Error("SHELL should be left alone without ExportShell")
+	}
+
+	output = runTransitionScript(t, t.TempDir(), &Options{Shell: "/usr/bin/env", ExportShell: true})
+	if !strings.Contains(output, "SHELL=/usr/bin/env\n") {
+		t.Errorf("Expected SHELL to follow the override, got:\n%s", output)
+	}
+
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+	if vars := shellEnvironment(shell, &Options{ExportShell: true}); vars != nil {
+		t.Errorf("SHELL should only be exported for overrides, got %v", vars)
+	}
+}
+
+// Test the scrubbed environment used by CleanEnvironment
+func TestExecEnvironment_Clean(t *testing.T) {
+	os.Setenv("AUTOCD_TEST_SECRET", "hunter2")
+	os.Setenv("AUTOCD_TEST_KEEP", "yes")
+	os.Setenv("LC_AUTOCD_TEST", "C")
+	defer os.Unsetenv("AUTOCD_TEST_SECRET")
+	defer os.Unsetenv("AUTOCD_TEST_KEEP")
+	defer os.Unsetenv("LC_AUTOCD_TEST")
+
+	env := strings.Join(execEnvironment(&Options{}), "\n")
+	if !strings.Contains(env, "AUTOCD_TEST_SECRET=hunter2") {
+		t.Error("Default environment should be inherited unchanged")
+	}
+
+	opts := &Options{CleanEnvironment: true, EnvAllowlist: []string{"AUTOCD_TEST_KEEP", "LC_*"}}
+	clean := execEnvironment(opts)
+	for _, entry := range clean {
+		name := entry[:strings.IndexByte(entry, '=')]
+		if !envNameAllowed(name, append(cleanEnvironmentAllowlist, opts.EnvAllowlist...)) {
+			t.Errorf("Unexpected variable in clean environment: %s", name)
+		}
+	}
+
+	joined := strings.Join(clean, "\n")
+	for _, want := range []string{"AUTOCD_TEST_KEEP=yes", "LC_AUTOCD_TEST=C"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %s in clean environment", want)
+		}
+	}
+	if strings.Contains(joined, "AUTOCD_TEST_SECRET") {
+		t.Error("Clean environment should drop variables outside the allowlist")
+	}
+}
+
+// Test adding and removing variables for the spawned shell
+func TestExecEnvironment_ExtraAndUnset(t *testing.T) {
+	os.Setenv("AUTOCD_TEST_TOKEN", "old")
+	os.Setenv("AUTOCD_TEST_DROP_A", "a")
+	os.Setenv("AUTOCD_TEST_DROP_B", "b")
+	defer os.Unsetenv("AUTOCD_TEST_TOKEN")
+	defer os.Unsetenv("AUTOCD_TEST_DROP_A")
+	defer os.Unsetenv("AUTOCD_TEST_DROP_B")
+
+	opts := &Options{
+		ExtraEnv: map[string]string{"AUTOCD_TEST_TOKEN": "new", "AUTOCD_TEST_SESSION": "42"},
+		UnsetEnv: []string{"AUTOCD_TEST_DROP_*", "AUTOCD_TEST_TOKEN"},
+	}
+	env := "\n" + strings.Join(execEnvironment(opts), "\n") + "\n"
+	for _, want := range []string{"\nAUTOCD_TEST_TOKEN=new\n", "\nAUTOCD_TEST_SESSION=42\n"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("Expected %q in the environment", strings.TrimSpace(want))
+		}
+	}
+	if strings.Contains(env, "AUTOCD_TEST_DROP_") || strings.Contains(env, "AUTOCD_TEST_TOKEN=old") {
+		t.Error("Unset variables should be removed")
+	}
+
+	if err := checkExtraEnv(&Options{ExtraEnv: map[string]string{"BAD=NAME": "x"}}); err == nil {
+		t.Error("Expected invalid variable names to be rejected")
+	}
+}
+
+// Test the HOME and SHELL fallbacks used in minimal environments
+func TestMinimalEnvironmentFallbacks(t *testing.T) {
+	uid := strconv.Itoa(os.Getuid())
+	passwd := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(passwd, []byte("me:x:"+uid+":"+uid+"::/home/me:/bin/sh\n"), 0644)
+
+	originalPasswd := passwdFile
+	passwdFile = passwd
+	defer func() { passwdFile = originalPasswd }()
+
+	t.Setenv("HOME", "")
+	t.Setenv("SHELL", "")
+
+	if vars := homeEnvironment(); len(vars) != 1 || vars[0].Value != "/home/me" {
+		t.Errorf("Expected HOME from passwd, got %v", vars)
+	}
+
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+	if vars := shellEnvironment(shell, &Options{}); len(vars) != 1 || vars[0].Value != "/bin/sh" {
+		t.Errorf("Expected SHELL to be the detected shell, got %v", vars)
+	}
+
+	message := describeMissingShell(&ShellInfo{}, "")
+	if !strings.Contains(message, "SHELL is unset") {
+		t.Errorf("Detection failure should mention the unset SHELL, got %q", message)
+	}
+
+	t.Setenv("HOME", "/somewhere")
+	if vars := homeEnvironment(); vars != nil {
+		t.Errorf("An existing HOME should be kept, got %v", vars)
+	}
+}
+
+// Test that the post-cd command runs in the target with the shell's environment
+func TestTransitionScript_PostCommand(t *testing.T) {
+	targetDir := t.TempDir()
+	output := runTransitionScript(t, targetDir, &Options{PlainOutput: true, PostCommand: `echo "ran in $(pwd) for $AUTOCD_TARGET_DIR"; exit 7`})
+
+	if !strings.Contains(output, "ran in "+targetDir+" for "+targetDir+"\n") {
+		t.Errorf("Expected the command to run in the target, got:\n%s", output)
+	}
+	if !strings.Contains(output, "AUTOCD_COMMAND_STATUS=7\n") {
+		t.Errorf("Expected the command status to reach the shell, got:\n%s", output)
+	}
+}
+
+// Test that the script exits with AppExitStatus instead of starting a shell
+// when there is no terminal
+func TestTransitionScript_ExitIfNonInteractive(t *testing.T) {
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+	script, err := generateScript(t.TempDir(), shell, &Options{AppExitStatus: 5, ExitIfNonInteractive: true, PlainOutput: true}, nil)
+	if err != nil {
+		t.Fatalf("Script generation failed: %v", err)
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", script)
+	cmd.Stdin = strings.NewReader("echo started\n")
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+		t.Errorf("Expected exit status 5, got %v", err)
+	}
+	if strings.Contains(string(output), "started") {
+		t.Error("The shell should not start without a terminal")
+	}
+}
+
+// Test that a transition without a terminal exits the process with
+// AppExitStatus
+func TestExitWithDirectory_ExitIfNonInteractive(t *testing.T) {
+	if checkTerminal(false, false) == nil {
+		t.Skip("requires running without a terminal")
+	}
+	stubExecve(t, func(string, []string, []string) error {
+		t.Fatal("execve must not be reached without a terminal")
+		return nil
+	})
+	exitCode := -1
+	originalExit := exitProcess
+	exitProcess = func(code int) { exitCode = code; panic("exit") }
+	defer func() { exitProcess = originalExit }()
+
+	func() {
+		defer func() { recover() }()
+		ExitWithDirectoryAdvanced(t.TempDir(), &Options{AppExitStatus: 7, ExitIfNonInteractive: true, DisableDepthWarnings: true})
+	}()
+	if exitCode != 7 {
+		t.Errorf("Expected exit status 7, got %d", exitCode)
+	}
+}
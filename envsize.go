@@ -0,0 +1,126 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// linuxMaxArgStrlen is MAX_ARG_STRLEN: the largest single argv/env string
+	linuxMaxArgStrlen = 32 * 4096
+
+	// defaultArgMax is used when the platform limit cannot be determined
+	defaultArgMax = 256 * 1024
+
+	// envReportLimit caps the number of offenders named in an error
+	envReportLimit = 5
+)
+
+// execArgMax returns the kernel limit on the combined size of argv and envp
+func execArgMax() int {
+	switch runtime.GOOS {
+	case "linux":
+		// Linux allows a quarter of the stack limit (at least 128 KiB)
+		if stack, ok := stackLimit(); ok {
+			if limit := stack / 4; limit >= 128*1024 && limit < 1<<31 {
+				return int(limit)
+			}
+		}
+		return 2 * 1024 * 1024
+	case "darwin", "freebsd", "netbsd", "openbsd":
+		return 256 * 1024
+	default:
+		return defaultArgMax
+	}
+}
+
+// execSize approximates the space argv and envp occupy at exec time:
+// each string plus its terminator and pointer slot
+func execSize(strs []string) int {
+	size := 0
+	for _, s := range strs {
+		size += len(s) + 1 + 8
+	}
+	return size
+}
+
+// guardExecSize detects environments that would make exec fail with E2BIG.
+// With trim set, the largest offending variables are dropped instead.
+func guardExecSize(args, env []string, trim bool, debugMode bool) ([]string, error) {
+	limit := execArgMax()
+	total := execSize(args) + execSize(env)
+
+	// Collect variables that are too large on their own
+	var offenders []string
+	if runtime.GOOS == "linux" {
+		for _, entry := range env {
+			if len(entry)+1 > linuxMaxArgStrlen {
+				offenders = append(offenders, entry)
+			}
+		}
+	}
+
+	if total <= limit && len(offenders) == 0 {
+		return env, nil
+	}
+
+	// Largest variables first, they are the most likely culprits
+	bySize := append([]string{}, env...)
+	sort.SliceStable(bySize, func(i, j int) bool { return len(bySize[i]) > len(bySize[j]) })
+
+	if !trim {
+		report := offenders
+		if len(report) == 0 {
+			report = bySize
+		}
+		return nil, fmt.Errorf("%w (%d bytes, limit %d): largest variables: %s",
+			ErrEnvironmentTooLarge, total, limit, describeEnvEntries(report))
+	}
+
+	drop := make(map[string]bool)
+	for _, entry := range offenders {
+		drop[entry] = true
+		total -= len(entry) + 1 + 8
+	}
+	for _, entry := range bySize {
+		if total <= limit {
+			break
+		}
+		if !drop[entry] {
+			drop[entry] = true
+			total -= len(entry) + 1 + 8
+		}
+	}
+
+	kept := make([]string, 0, len(env))
+	for _, entry := range env {
+		if drop[entry] {
+			if debugMode {
+				fmt.Fprintf(os.Stderr, "autocd: dropping oversized variable %s\n", describeEnvEntries([]string{entry}))
+			}
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, nil
+}
+
+// describeEnvEntries names variables with their sizes, e.g. "BIG (500000 bytes)"
+func describeEnvEntries(entries []string) string {
+	if len(entries) > envReportLimit {
+		entries = entries[:envReportLimit]
+	}
+
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry
+		if i := strings.IndexByte(entry, '='); i >= 0 {
+			name = entry[:i]
+		}
+		parts = append(parts, fmt.Sprintf("%s (%d bytes)", name, len(entry)))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package autocd
+
+// stackLimit is not available without getrlimit(2)
+func stackLimit() (uint64, bool) {
+	return 0, false
+}
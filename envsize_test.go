@@ -0,0 +1,42 @@
+package autocd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test that small environments pass through unchanged
+func TestGuardExecSize_WithinLimit(t *testing.T) {
+	env := []string{"HOME=/home/user", "PATH=/usr/bin"}
+	got, err := guardExecSize([]string{"/bin/sh", "script.sh"}, env, false, false)
+	if err != nil {
+		t.Fatalf("guardExecSize failed: %v", err)
+	}
+	if len(got) != len(env) {
+		t.Errorf("Environment should be unchanged, got %v", got)
+	}
+}
+
+// Test oversized environments are reported by name or trimmed on request
+func TestGuardExecSize_Oversized(t *testing.T) {
+	huge := "HUGE=" + strings.Repeat("x", execArgMax())
+	env := []string{"HOME=/home/user", huge, "PATH=/usr/bin"}
+	args := []string{"/bin/sh", "script.sh"}
+
+	_, err := guardExecSize(args, env, false, false)
+	if !errors.Is(err, ErrEnvironmentTooLarge) {
+		t.Fatalf("Expected ErrEnvironmentTooLarge, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "HUGE (") {
+		t.Errorf("Error should name the offending variable: %v", err)
+	}
+
+	trimmed, err := guardExecSize(args, env, true, false)
+	if err != nil {
+		t.Fatalf("Trimming should succeed: %v", err)
+	}
+	if len(trimmed) != 2 || trimmed[0] != "HOME=/home/user" || trimmed[1] != "PATH=/usr/bin" {
+		t.Errorf("Expected only the oversized variable to be dropped, got %d entries", len(trimmed))
+	}
+}
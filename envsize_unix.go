@@ -0,0 +1,14 @@
+//go:build unix
+
+package autocd
+
+import "syscall"
+
+// stackLimit returns the soft stack size limit (RLIMIT_STACK)
+func stackLimit() (uint64, bool) {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_STACK, &rlim); err != nil {
+		return 0, false
+	}
+	return uint64(rlim.Cur), true
+}
@@ -3,6 +3,7 @@ package autocd
 import (
 	"errors"
 	"fmt"
+	"syscall"
 )
 
 // Exported error variables for specific validation failures
@@ -11,8 +12,74 @@ var (
 	ErrPathNotDirectory  = errors.New("path is not a directory")
 	ErrPathNotAccessible = errors.New("path is not accessible")
 	ErrSecurityViolation = errors.New("security violation")
+	ErrPathTooLong       = errors.New("path too long")
+	ErrShellNotAllowed   = fmt.Errorf("%w: shell not allowed", ErrSecurityViolation)
+	ErrUnsafePrivileges  = fmt.Errorf("%w: unsafe setuid/setgid execution", ErrSecurityViolation)
+	ErrRunningAsRoot     = fmt.Errorf("%w: refusing to spawn a root shell", ErrSecurityViolation)
+	ErrPolicyViolation   = fmt.Errorf("%w: path policy", ErrSecurityViolation)
+	ErrControlCharacter  = fmt.Errorf("%w: control character in path", ErrSecurityViolation)
+
+	ErrEnvironmentTooLarge = errors.New("environment too large for exec")
+	ErrNoTerminal          = errors.New("no terminal available for an interactive shell")
+	ErrScriptQuotaExceeded = errors.New("autocd script quota exceeded")
+	ErrNoDirectoryReported = errors.New("command did not report a directory")
+	ErrTargetUnresolved    = errors.New("symbolic target could not be resolved")
+	ErrPendingWork         = errors.New("pending work would be lost on exec")
+	ErrPrepareTimeout      = errors.New("transition preparation exceeded its time budget")
+	ErrDepthLimit          = errors.New("shell nesting limit reached")
 )
 
+// ExecError describes a failed process replacement with a human explanation
+type ExecError struct {
+	Executable  string        // Program that could not be executed
+	Errno       syscall.Errno // Underlying errno from exec
+	Explanation string        // Likely cause in plain words
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("exec %s: %s (%v)", e.Executable, e.Explanation, e.Errno)
+}
+
+// Unwrap returns the errno so errors.Is(err, syscall.EACCES) keeps working.
+func (e *ExecError) Unwrap() error {
+	return e.Errno
+}
+
+// explainExecError maps an exec errno to an ExecError; other errors are
+// returned unchanged
+func explainExecError(executable string, err error) error {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return err
+	}
+
+	// Explanations describe the executable named in the error; for the
+	// transition that is /bin/sh or the shell, never the script it reads
+	var explanation string
+	switch errno {
+	case syscall.ENOENT:
+		explanation = "executable or its interpreter is missing"
+	case syscall.EACCES:
+		explanation = "permission denied; the file is not executable, a parent directory is not searchable, or its filesystem is mounted noexec"
+	case syscall.ENOEXEC:
+		explanation = "not a valid executable for this system"
+	case syscall.E2BIG:
+		explanation = "argument list and environment are too large"
+	case syscall.ETXTBSY:
+		explanation = "executable is still open for writing"
+	case syscall.EPERM:
+		explanation = "operation not permitted by security policy (e.g. SELinux, AppArmor, nosuid mount)"
+	case syscall.ELOOP:
+		explanation = "too many symbolic links while resolving the executable"
+	case syscall.ENOMEM:
+		explanation = "not enough memory to start the executable"
+	default:
+		explanation = "unexpected exec failure"
+	}
+
+	return &ExecError{Executable: executable, Errno: errno, Explanation: explanation}
+}
+
 // Helper functions for common error cases
 func newPathError(errType ErrorType, path string, cause error) *AutoCDError {
 	return &AutoCDError{
@@ -34,6 +101,8 @@ func newPathValidationError(path string, cause error) *AutoCDError {
 		errType = ErrorPathNotAccessible
 	case errors.Is(cause, ErrSecurityViolation):
 		errType = ErrorSecurityViolation
+	case errors.Is(cause, ErrPathTooLong):
+		errType = ErrorPathTooLong
 	}
 
 	return &AutoCDError{
@@ -53,6 +122,60 @@ func newShellDetectionError(message string) *AutoCDError {
 	}
 }
 
+func newSecurityError(path string, cause error) *AutoCDError {
+	return &AutoCDError{
+		Type:    ErrorSecurityViolation,
+		Message: fmt.Sprintf("autocd: security check failed: %v", cause),
+		Path:    path,
+		Cause:   cause,
+	}
+}
+
+func newShellSecurityError(shellPath string, cause error) *AutoCDError {
+	return &AutoCDError{
+		Type:    ErrorSecurityViolation,
+		Message: fmt.Sprintf("autocd: shell validation failed: %v", cause),
+		Path:    shellPath,
+		Cause:   cause,
+	}
+}
+
+func newTerminalError(cause error) *AutoCDError {
+	return &AutoCDError{
+		Type:    ErrorScriptExecution,
+		Message: fmt.Sprintf("autocd: terminal check failed: %v", cause),
+		Path:    "",
+		Cause:   cause,
+	}
+}
+
+func newTimeoutError(cause error) *AutoCDError {
+	return &AutoCDError{
+		Type:    ErrorTimeout,
+		Message: fmt.Sprintf("autocd: %v", cause),
+		Path:    "",
+		Cause:   cause,
+	}
+}
+
+func newVetoError(cause error) *AutoCDError {
+	return &AutoCDError{
+		Type:    ErrorVetoed,
+		Message: fmt.Sprintf("autocd: transition cancelled: %v", cause),
+		Path:    "",
+		Cause:   cause,
+	}
+}
+
+func newDepthError(depth, limit int) *AutoCDError {
+	return &AutoCDError{
+		Type:    ErrorDepthLimit,
+		Message: fmt.Sprintf("autocd: %v (%d nested shells, limit %d)", ErrDepthLimit, depth, limit),
+		Path:    "",
+		Cause:   ErrDepthLimit,
+	}
+}
+
 func newScriptGenerationError(cause error) *AutoCDError {
 	return &AutoCDError{
 		Type:    ErrorScriptGeneration,
@@ -87,7 +210,8 @@ func IsPathError(err error) bool {
 		return autoCDErr.Type == ErrorPathNotFound ||
 			autoCDErr.Type == ErrorPathNotDirectory ||
 			autoCDErr.Type == ErrorPathNotAccessible ||
-			autoCDErr.Type == ErrorSecurityViolation
+			autoCDErr.Type == ErrorSecurityViolation ||
+			autoCDErr.Type == ErrorPathTooLong
 	}
 	return false
 }
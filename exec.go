@@ -1,14 +1,18 @@
 package autocd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"syscall"
+	"time"
 )
 
 // executeScript replaces current process with script using Unix syscall.Exec
-func executeScript(scriptPath string, shell *ShellInfo, debugMode bool) error {
-	if debugMode {
+func executeScript(scriptPath string, shell *ShellInfo, opts *Options) error {
+	if opts.DebugMode {
 		fmt.Fprintf(os.Stderr, "autocd: executing script %s (target shell: %s)\n", scriptPath, shell.Path)
 	}
 
@@ -16,15 +20,138 @@ func executeScript(scriptPath string, shell *ShellInfo, debugMode bool) error {
 	// This fixes fish compatibility and other exotic shells
 	// The script will exec into the user's shell at the end
 	executable := "/bin/sh"
+	scriptPath, releaseFD, err := inheritScriptFD(scriptPath)
+	if err != nil {
+		return err
+	}
+	defer releaseFD()
 	args := []string{executable, scriptPath}
 
+	// Refuse (or trim) environments the kernel would reject with E2BIG
+	env, err := guardExecSize(args, execEnvironment(opts), opts.TrimOversizedEnv, opts.DebugMode)
+	if err != nil {
+		return err
+	}
+
+	// Detach from the caller's process group so supervisors signalling
+	// that group don't take the inherited shell down with it
+	if opts.NewSession {
+		if err := startNewSession(opts); err != nil {
+			return err
+		}
+	}
+
 	// Replace current process with Unix syscall.Exec
-	return syscall.Exec(executable, args, os.Environ())
+	err = execWithRetry(executable, args, env)
+
+	// An exotic /bin/sh may not be executable here at all; try running the
+	// same POSIX script with another compatible interpreter
+	if errors.Is(err, syscall.ENOEXEC) {
+		for _, interpreter := range fallbackInterpreters(executable, shell) {
+			if opts.DebugMode {
+				fmt.Fprintf(os.Stderr, "autocd: %s failed with ENOEXEC, retrying with %s\n", executable, interpreter)
+			}
+			execve(interpreter, []string{interpreter, scriptPath}, env)
+		}
+	}
+
+	return explainExecError(executable, err)
+}
+
+// sameDirectoryExec reports whether the shell can be exec'd directly for
+// targetDir: it is already the working directory and nothing needs the
+// script (terminal sequences, a sudo handoff)
+func sameDirectoryExec(targetDir string, opts *Options, launch *shellLaunch) bool {
+	if launch.RunAs != nil || renderTerminalSequences(targetDir, opts) != "" {
+		return false
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	if filepath.Clean(cwd) == targetDir {
+		return true
+	}
+	cwdInfo, err := os.Stat(cwd)
+	if err != nil {
+		return false
+	}
+	targetInfo, err := os.Stat(targetDir)
+	return err == nil && os.SameFile(cwdInfo, targetInfo)
+}
+
+// execShellDirect replaces the current process with the shell itself,
+// with the environment the transition script would have exported
+func execShellDirect(targetDir string, shell *ShellInfo, opts *Options, launch *shellLaunch) error {
+	if opts.DebugMode {
+		fmt.Fprintf(os.Stderr, "autocd: already in %s, executing %s directly\n", targetDir, shell.Path)
+	}
+
+	args := append([]string{shell.Path}, launchArgs(shell, opts, launch)...)
+	env := applyExports(execEnvironment(opts), transitionEnvironment(targetDir, shell, opts, launch))
+	env, err := guardExecSize(args, env, opts.TrimOversizedEnv, opts.DebugMode)
+	if err != nil {
+		return err
+	}
+
+	if opts.NewSession {
+		if err := startNewSession(opts); err != nil {
+			return err
+		}
+	}
+
+	return explainExecError(shell.Path, execve(shell.Path, args, env))
+}
+
+// fallbackInterpreters lists alternatives to /bin/sh for running the
+// script: the user's shell when it speaks POSIX sh, then sh found in PATH
+func fallbackInterpreters(failed string, shell *ShellInfo) []string {
+	var candidates []string
+	seen := map[string]bool{failed: true}
+
+	if shell.Type().Capabilities().POSIX && !seen[shell.Path] {
+		candidates = append(candidates, shell.Path)
+		seen[shell.Path] = true
+	}
+	if path, err := exec.LookPath("sh"); err == nil && !seen[path] {
+		candidates = append(candidates, path)
+	}
+	return candidates
+}
+
+// execve is the process replacement primitive (replaceable in tests)
+var execve = syscall.Exec
+
+// ETXTBSY retry policy: a freshly written script can briefly be reported
+// busy while another thread's fork still holds the write descriptor
+const (
+	execBusyRetries    = 5
+	execBusyInitialGap = 5 * time.Millisecond
+)
+
+// execWithRetry calls execve, retrying with exponential backoff while the
+// kernel reports ETXTBSY for the script we have just created
+func execWithRetry(executable string, args, env []string) error {
+	delay := execBusyInitialGap
+	for attempt := 0; ; attempt++ {
+		err := execve(executable, args, env)
+		if !errors.Is(err, syscall.ETXTBSY) || attempt >= execBusyRetries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
 }
 
 // ExecReplacement handles the actual process replacement
 // This is the core function that never returns on success
 func ExecReplacement(scriptPath string, shell *ShellInfo, debugMode bool) error {
+	return execReplacement(scriptPath, shell, &Options{DebugMode: debugMode})
+}
+
+// execReplacement validates its inputs and execs the script with the
+// environment and behavior described by opts
+func execReplacement(scriptPath string, shell *ShellInfo, opts *Options) error {
 	// Validate inputs
 	if scriptPath == "" {
 		return newPathError(ErrorPathNotFound, "", fmt.Errorf("script path is empty"))
@@ -47,10 +174,19 @@ func ExecReplacement(scriptPath string, shell *ShellInfo, debugMode bool) error
 	if info.IsDir() {
 		return newPathError(ErrorPathNotDirectory, scriptPath, fmt.Errorf("script path is a directory"))
 	}
-	if info.Mode()&0111 == 0 {
+	// In-memory scripts arrive through a pipe, which has no execute bits
+	if info.Mode()&os.ModeNamedPipe == 0 && info.Mode()&0111 == 0 {
 		return newPathError(ErrorPathNotAccessible, scriptPath, fmt.Errorf("script file is not executable"))
 	}
 
+	// With elevated effective IDs, make sure nobody else could have
+	// replaced the script since it was written
+	if isPrivileged() {
+		if err := checkScriptOwnership(scriptPath); err != nil {
+			return newSecurityError(scriptPath, err)
+		}
+	}
+
 	// Execute the script - this should never return
-	return executeScript(scriptPath, shell, debugMode)
+	return executeScript(scriptPath, shell, opts)
 }
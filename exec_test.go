@@ -0,0 +1,140 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// stubExecve replaces the exec primitive for the duration of a test
+func stubExecve(t *testing.T, fn func(string, []string, []string) error) {
+	t.Helper()
+	original := execve
+	execve = fn
+	t.Cleanup(func() { execve = original })
+}
+
+// Test that transient ETXTBSY failures are retried
+func TestExecWithRetry_TextBusy(t *testing.T) {
+	calls := 0
+	stubExecve(t, func(string, []string, []string) error {
+		calls++
+		if calls < 3 {
+			return syscall.ETXTBSY
+		}
+		return syscall.ENOENT
+	})
+
+	err := execWithRetry("/bin/sh", nil, nil)
+	if !errors.Is(err, syscall.ENOENT) || calls != 3 {
+		t.Errorf("Expected retry until a non-ETXTBSY error, got %v after %d calls", err, calls)
+	}
+}
+
+// Test that retries are bounded
+func TestExecWithRetry_Bounded(t *testing.T) {
+	calls := 0
+	stubExecve(t, func(string, []string, []string) error {
+		calls++
+		return syscall.ETXTBSY
+	})
+
+	err := execWithRetry("/bin/sh", nil, nil)
+	if !errors.Is(err, syscall.ETXTBSY) || calls != execBusyRetries+1 {
+		t.Errorf("Expected %d attempts ending in ETXTBSY, got %v after %d calls", execBusyRetries+1, err, calls)
+	}
+}
+
+// Test interpreter selection after ENOEXEC
+func TestFallbackInterpreters(t *testing.T) {
+	candidates := fallbackInterpreters("/bin/sh", &ShellInfo{Path: "/usr/bin/bash", IsValid: true})
+	if len(candidates) == 0 || candidates[0] != "/usr/bin/bash" {
+		t.Errorf("Expected the POSIX-compatible user shell first, got %v", candidates)
+	}
+
+	for _, candidate := range fallbackInterpreters("/bin/sh", &ShellInfo{Path: "/usr/bin/fish", IsValid: true}) {
+		if candidate == "/usr/bin/fish" || candidate == "/bin/sh" {
+			t.Errorf("Unexpected fallback interpreter %s", candidate)
+		}
+	}
+}
+
+// Test that ENOEXEC triggers the fallback interpreter with the script argument
+func TestExecuteScript_ENOEXECFallback(t *testing.T) {
+	var attempts [][]string
+	stubExecve(t, func(argv0 string, args []string, env []string) error {
+		attempts = append(attempts, args)
+		return syscall.ENOEXEC
+	})
+
+	shell := &ShellInfo{Path: "/usr/bin/bash", IsValid: true}
+	err := executeScript("/tmp/autocd_test.sh", shell, &Options{})
+	if !errors.Is(err, syscall.ENOEXEC) {
+		t.Errorf("Expected the original ENOEXEC error, got: %v", err)
+	}
+	if len(attempts) < 2 || attempts[1][0] != "/usr/bin/bash" || attempts[1][1] != "/tmp/autocd_test.sh" {
+		t.Errorf("Expected a retry with the user shell, got %v", attempts)
+	}
+}
+
+// Test that a transition into the working directory execs the shell directly
+func TestExitWithDirectory_DirectExecSameDir(t *testing.T) {
+	target := t.TempDir()
+	scripts := t.TempDir()
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	if err := os.Chdir(target); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+	defer os.Chdir(original)
+
+	var argv []string
+	var environ []string
+	stubExecve(t, func(argv0 string, args []string, env []string) error {
+		argv, environ = args, env
+		return syscall.EACCES
+	})
+
+	opts := &Options{
+		Shell:                "/bin/sh",
+		TempDir:              scripts,
+		SkipTTYCheck:         true,
+		DisableCleanup:       true,
+		DisableDepthWarnings: true,
+		DirectExecSameDir:    true,
+	}
+	if err := ExitWithDirectoryAdvanced(target, opts); err == nil {
+		t.Fatal("Expected the stubbed exec failure")
+	}
+	if len(argv) != 1 || argv[0] != "/bin/sh" {
+		t.Errorf("Expected the shell to be exec'd without a script, got %v", argv)
+	}
+	if !strings.Contains(strings.Join(environ, "\n"), "AUTOCD_TARGET_DIR="+target+"\n") {
+		t.Error("Expected the transition environment on the direct exec")
+	}
+	if entries, _ := os.ReadDir(scripts); len(entries) != 0 {
+		t.Errorf("No script should be written, found %d files", len(entries))
+	}
+
+	// Elsewhere the script is still used
+	other := t.TempDir()
+	if err := ExitWithDirectoryAdvanced(other, opts); err == nil {
+		t.Fatal("Expected the stubbed exec failure")
+	}
+	if len(argv) != 2 || !strings.HasPrefix(filepath.Base(argv[1]), "autocd_") {
+		t.Errorf("Expected the transition script for another directory, got %v", argv)
+	}
+}
+
+// Test that exports replace inherited variables in place
+func TestApplyExports(t *testing.T) {
+	env := applyExports([]string{"A=1", "B=2"}, []envVar{{"B", "3"}, {"C", "4"}, {"B", "5"}})
+	if strings.Join(env, " ") != "A=1 B=5 C=4" {
+		t.Errorf("Unexpected environment: %v", env)
+	}
+}
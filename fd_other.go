@@ -0,0 +1,33 @@
+//go:build !unix
+
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// writeFD is unsupported where inherited descriptors are not plain numbers
+func writeFD(fd int, p []byte) (int, error) {
+	return 0, fmt.Errorf("writing to descriptor %d is not supported on %s", fd, runtime.GOOS)
+}
+
+// fdOpen reports every descriptor closed, so optional descriptor outputs
+// are skipped
+func fdOpen(fd int) bool {
+	return false
+}
+
+// scriptFDPath is unsupported where scripts cannot be passed to /bin/sh
+// as /dev/fd paths
+func scriptFDPath(f *os.File) (string, func(), error) {
+	f.Close()
+	return "", nil, fmt.Errorf("sharing the script descriptor is not supported on %s", runtime.GOOS)
+}
+
+// inheritScriptFD has no descriptor to hand over here; scriptFDPath never
+// returns /dev/fd paths
+func inheritScriptFD(scriptPath string) (string, func(), error) {
+	return scriptPath, func() {}, nil
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// writeFD writes p to a raw file descriptor. Wrapping the descriptor in an
+// *os.File would close the caller's descriptor when garbage collected.
+func writeFD(fd int, p []byte) (int, error) {
+	return syscall.Write(fd, p)
+}
+
+// fdOpen reports whether a file descriptor is open
+func fdOpen(fd int) bool {
+	var st syscall.Stat_t
+	return syscall.Fstat(fd, &st) == nil
+}
+
+// scriptFDPath returns a /dev/fd path naming f, so /bin/sh can read the
+// script through the descriptor. f keeps FD_CLOEXEC, so children started
+// before the exec (the shell probe, getent, tmux) do not inherit it; it
+// stays open until the release function runs.
+func scriptFDPath(f *os.File) (string, func(), error) {
+	return "/dev/fd/" + strconv.Itoa(int(f.Fd())), func() { f.Close() }, nil
+}
+
+// inheritScriptFD prepares scriptPath for execve. A /dev/fd path names a
+// close-on-exec descriptor, so it is duplicated without the flag right
+// before the exec; the script closes the duplicate once it has been
+// opened. Other paths are returned unchanged. release closes the
+// duplicate when the exec fails.
+func inheritScriptFD(scriptPath string) (string, func(), error) {
+	if !strings.HasPrefix(scriptPath, "/dev/fd/") {
+		return scriptPath, func() {}, nil
+	}
+	fd, err := strconv.Atoi(strings.TrimPrefix(scriptPath, "/dev/fd/"))
+	if err != nil {
+		return scriptPath, func() {}, nil
+	}
+	inherited, err := syscall.Dup(fd)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to share script descriptor: %w", err)
+	}
+	return "/dev/fd/" + strconv.Itoa(inherited), func() { syscall.Close(inherited) }, nil
+}
@@ -0,0 +1,49 @@
+package autocd
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// fileManagerOpener is the command that shows a directory in the desktop
+// file manager (replaceable in tests)
+var fileManagerOpener = defaultFileManagerOpener()
+
+// fileManagerStartup is how long an opener may take to fail; one still
+// running after it is assumed to be showing the directory
+const fileManagerStartup = 2 * time.Second
+
+// defaultFileManagerOpener returns the platform's "open" command
+func defaultFileManagerOpener() string {
+	if runtime.GOOS == "darwin" {
+		return "open"
+	}
+	return "xdg-open"
+}
+
+// openInFileManager shows dir in the desktop file manager
+func openInFileManager(dir string) error {
+	path, err := exec.LookPath(fileManagerOpener)
+	if err != nil {
+		return fmt.Errorf("no file manager opener: %w", err)
+	}
+
+	cmd := exec.Command(path, dir)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", fileManagerOpener, err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", fileManagerOpener, dir, err)
+		}
+	case <-time.After(fileManagerStartup):
+		// Some openers stay in the foreground; leave it running
+	}
+	return nil
+}
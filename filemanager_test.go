@@ -0,0 +1,40 @@
+package autocd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test opening a directory through the file manager opener
+func TestOpenInFileManager(t *testing.T) {
+	dir := t.TempDir()
+	record := filepath.Join(dir, "opened")
+	opener := filepath.Join(dir, "opener")
+	script := "#!/bin/sh\nprintf '%s' \"$1\" > '" + record + "'\n"
+	if err := os.WriteFile(opener, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write opener: %v", err)
+	}
+
+	original := fileManagerOpener
+	defer func() { fileManagerOpener = original }()
+
+	fileManagerOpener = opener
+	if err := openInFileManager(dir); err != nil {
+		t.Fatalf("openInFileManager failed: %v", err)
+	}
+	if got, _ := os.ReadFile(record); string(got) != dir {
+		t.Errorf("Expected the opener to receive %s, got %q", dir, got)
+	}
+
+	fileManagerOpener = "/bin/false"
+	if err := openInFileManager(dir); err == nil || !strings.Contains(err.Error(), "/bin/false") {
+		t.Errorf("Expected a failing opener to be reported, got: %v", err)
+	}
+
+	fileManagerOpener = "autocd-no-such-opener"
+	if err := openInFileManager(dir); err == nil {
+		t.Error("Expected an error for a missing opener")
+	}
+}
@@ -0,0 +1,41 @@
+package autocd
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// linuxFilesystemMagic maps common statfs f_type values to names
+var linuxFilesystemMagic = map[int64]string{
+	0xEF53:     "ext4",
+	0x9123683E: "btrfs",
+	0x58465342: "xfs",
+	0x01021994: "tmpfs",
+	0x6969:     "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x794C7630: "overlay",
+	0x65735546: "fuse",
+	0x2FC12FC1: "zfs",
+	0x4D44:     "vfat",
+	0x5346544E: "ntfs",
+	0x9FA0:     "proc",
+	0x62656572: "sysfs",
+	0x0187:     "autofs",
+	0x73717368: "squashfs",
+	0x9660:     "iso9660",
+	0x858458F6: "ramfs",
+}
+
+// filesystemType returns the name of the filesystem containing path
+func filesystemType(path string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return ""
+	}
+	magic := int64(st.Type) & 0xFFFFFFFF
+	if name, ok := linuxFilesystemMagic[magic]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", magic)
+}
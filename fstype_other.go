@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package autocd
+
+// filesystemType is not implemented on this platform
+func filesystemType(path string) string {
+	return ""
+}
@@ -0,0 +1,80 @@
+package autocd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GenerateShellIntegration returns a wrapper function for the running
+// application, to be eval'd from the user's rc file, e.g.
+//
+//	eval "$(mytool init bash)"
+//	mytool init fish | source
+//
+// The wrapper runs the application with AUTOCD_LAST_DIR_PATH pointing at a
+// temporary file and cd's to the directory written there, so transitions
+// happen in the user's own shell instead of a nested one. shellName is
+// "bash", "zsh", "sh", "dash", "ksh" or "fish". Names with dashes
+// (my-tool) are accepted for bash, zsh and fish; sh, dash and ksh only
+// accept plain identifiers.
+func GenerateShellIntegration(shellName string) (string, error) {
+	name := appName(nil)
+	pattern := validFunctionName
+	switch shellName {
+	case "bash", "zsh", "fish":
+		pattern = dashedFunctionName
+	}
+	if !pattern.MatchString(name) {
+		return "", fmt.Errorf("autocd: %q cannot be used as a shell function name", name)
+	}
+
+	switch shellName {
+	case "bash", "zsh", "sh", "dash", "ksh":
+		return posixIntegration(name), nil
+	case "fish":
+		return fishIntegration(name), nil
+	default:
+		return "", fmt.Errorf("autocd: no shell integration for %q", shellName)
+	}
+}
+
+// dashedFunctionName matches the function names bash, zsh and fish accept
+// beyond validFunctionName: dashes after the first character
+var dashedFunctionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// posixIntegration renders the wrapper for POSIX shells; it avoids local so
+// dash and ksh accept it
+func posixIntegration(name string) string {
+	return strings.NewReplacer("NAME", name, "PROGRAM", shellQuote(name), "ENVVAR", LastDirPathEnv).Replace(
+		`NAME() {
+    __autocd_tmp="$(mktemp -t autocd.XXXXXX)" || return
+    ENVVAR="$__autocd_tmp" command PROGRAM "$@"
+    __autocd_status=$?
+    __autocd_dir="$(cat -- "$__autocd_tmp")"
+    rm -f -- "$__autocd_tmp"
+    if [ -n "$__autocd_dir" ] && [ -d "$__autocd_dir" ] && [ "$__autocd_dir" != "$PWD" ]; then
+        cd -- "$__autocd_dir" || __autocd_status=$?
+    fi
+    unset __autocd_tmp __autocd_dir
+    return $__autocd_status
+}
+`)
+}
+
+// fishIntegration renders the wrapper for fish
+func fishIntegration(name string) string {
+	return strings.NewReplacer("NAME", name, "PROGRAM", fishQuote(name), "ENVVAR", LastDirPathEnv).Replace(
+		`function NAME
+    set -l tmp (mktemp -t autocd.XXXXXX); or return
+    env ENVVAR=$tmp PROGRAM $argv
+    set -l code $status
+    set -l dir (cat -- $tmp)
+    rm -f -- $tmp
+    if test -n "$dir" -a -d "$dir" -a "$dir" != "$PWD"
+        cd $dir; or set code $status
+    end
+    return $code
+end
+`)
+}
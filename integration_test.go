@@ -0,0 +1,75 @@
+package autocd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test the generated wrapper cd'ing the user's shell after the program exits
+func TestGenerateShellIntegration(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+
+	bin := t.TempDir()
+	target := t.TempDir()
+	program := "#!/bin/sh\nprintf '%s' '" + target + "' > \"$AUTOCD_LAST_DIR_PATH\"\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(bin, "fakecd"), []byte(program), 0755); err != nil {
+		t.Fatalf("Failed to write program: %v", err)
+	}
+	os.Args = append([]string{filepath.Join(bin, "fakecd")}, originalArgs[1:]...)
+
+	integration, err := GenerateShellIntegration("sh")
+	if err != nil {
+		t.Fatalf("GenerateShellIntegration failed: %v", err)
+	}
+	cmd := exec.Command("/bin/sh", "-c", integration+"fakecd; echo \"$? $PWD\"")
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Wrapper failed: %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "3 "+target {
+		t.Errorf("Expected the shell in %s with status 3, got %q", target, output)
+	}
+
+	if fish, err := GenerateShellIntegration("fish"); err != nil || !strings.HasPrefix(fish, "function fakecd\n") {
+		t.Errorf("Unexpected fish integration (%v):\n%s", err, fish)
+	}
+	if _, err := GenerateShellIntegration("tcsh"); err == nil {
+		t.Error("Expected an error for unsupported shells")
+	}
+
+	os.Args[0] = "/usr/bin/my tool"
+	if _, err := GenerateShellIntegration("bash"); err == nil {
+		t.Error("Expected an error for names that are not valid functions")
+	}
+}
+
+// Test that dashed program names get a wrapper in shells that accept them
+func TestGenerateShellIntegration_DashedName(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+	os.Args = append([]string{"/usr/bin/my-tool"}, originalArgs[1:]...)
+
+	if _, err := GenerateShellIntegration("sh"); err == nil {
+		t.Error("Expected an error for a dashed name in sh")
+	}
+	if fish, err := GenerateShellIntegration("fish"); err != nil || !strings.HasPrefix(fish, "function my-tool\n") {
+		t.Errorf("Unexpected fish integration (%v):\n%s", err, fish)
+	}
+
+	integration, err := GenerateShellIntegration("bash")
+	if err != nil {
+		t.Fatalf("GenerateShellIntegration failed: %v", err)
+	}
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	if output, err := exec.Command(bash, "-c", integration+"type -t my-tool").CombinedOutput(); err != nil || strings.TrimSpace(string(output)) != "function" {
+		t.Errorf("bash should define the dashed wrapper (%v): %s", err, output)
+	}
+}
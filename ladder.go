@@ -0,0 +1,122 @@
+package autocd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// errNoSentinelFile reports StrategySentinelFile without Options.SentinelFile
+var errNoSentinelFile = errors.New("no sentinel file configured")
+
+// errNotInTmux reports StrategyTmux outside a tmux session
+var errNotInTmux = errors.New("not inside tmux")
+
+// String returns the strategy name used in debug output
+func (s Strategy) String() string {
+	switch s {
+	case StrategyExec:
+		return "exec"
+	case StrategySpawn:
+		return "spawn"
+	case StrategyTmux:
+		return "tmux"
+	case StrategyPrintPath:
+		return "print-path"
+	case StrategySentinelFile:
+		return "sentinel-file"
+	default:
+		return fmt.Sprintf("strategy(%d)", int(s))
+	}
+}
+
+// runStrategies walks Options.Strategies until one takes over the process.
+// A path error ends the walk, since no strategy can reach an invalid
+// target; otherwise the last failure is returned.
+func runStrategies(targetPath string, opts *Options, presetShell *ShellInfo) error {
+	var err error
+	for _, strategy := range opts.Strategies {
+		err = attemptStrategy(strategy, targetPath, opts, presetShell)
+		reportStrategy(opts, strategy, err)
+		if opts.DebugMode {
+			fmt.Fprintf(os.Stderr, "autocd: strategy %s failed: %v\n", strategy, err)
+		}
+		if IsPathError(err) {
+			break
+		}
+	}
+	return err
+}
+
+// attemptStrategy runs one strategy; it only returns when the strategy failed
+func attemptStrategy(strategy Strategy, targetPath string, opts *Options, presetShell *ShellInfo) error {
+	switch strategy {
+	case StrategyExec:
+		return execTransition(targetPath, opts, presetShell)
+	case StrategySpawn:
+		return spawnTransition(targetPath, opts, presetShell)
+	}
+
+	// The remaining strategies only need the validated directory
+	dir, err := resolveAndValidate(targetPath, opts.SecurityLevel)
+	if err != nil {
+		return newPathValidationError(targetPath, err)
+	}
+
+	switch strategy {
+	case StrategyTmux:
+		if !insideTmux() {
+			return newScriptExecutionError(errNotInTmux)
+		}
+		reportStrategy(opts, strategy, nil)
+		if err := runTmux("new-window", "-c", dir); err != nil {
+			return newScriptExecutionError(err)
+		}
+	case StrategyPrintPath:
+		reportStrategy(opts, strategy, nil)
+		if _, err := fmt.Fprintln(os.Stdout, dir); err != nil {
+			return newScriptExecutionError(err)
+		}
+	case StrategySentinelFile:
+		if opts.SentinelFile == "" {
+			return newScriptCreationError(errNoSentinelFile)
+		}
+		reportStrategy(opts, strategy, nil)
+		if err := writeLastDirFile(dir, opts.SentinelFile); err != nil {
+			return err
+		}
+	default:
+		return newScriptExecutionError(fmt.Errorf("unknown %s", strategy))
+	}
+
+	exitProcess(opts.AppExitStatus)
+	return nil
+}
+
+// spawnTransition runs the transition script as a child process instead of
+// exec'ing it, then exits with the shell's status
+func spawnTransition(targetPath string, opts *Options, presetShell *ShellInfo) error {
+	cmd, cleanup, err := buildCommand(targetPath, opts, presetShell)
+	if err != nil {
+		return err
+	}
+
+	reportStrategy(opts, StrategySpawn, nil)
+	err = cmd.Run()
+	cleanup()
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return newScriptExecutionError(err)
+	}
+	exitProcess(cmd.ProcessState.ExitCode())
+	return nil
+}
+
+// reportStrategy notifies Options.OnStrategy, if set
+func reportStrategy(opts *Options, strategy Strategy, err error) {
+	if opts.OnStrategy != nil {
+		opts.OnStrategy(strategy, err)
+	}
+}
@@ -0,0 +1,56 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test walking the strategy ladder past failing strategies
+func TestRunStrategies(t *testing.T) {
+	originalTmux, had := os.LookupEnv("TMUX")
+	defer restoreEnv("TMUX", originalTmux, had)
+	os.Unsetenv("TMUX")
+
+	exitCode := -1
+	originalExit := exitProcess
+	exitProcess = func(code int) { exitCode = code; panic("exit") }
+	defer func() { exitProcess = originalExit }()
+
+	var events []string
+	target := t.TempDir()
+	sentinel := filepath.Join(t.TempDir(), "sentinel")
+	opts := &Options{
+		Strategies:           []Strategy{StrategyTmux, StrategySentinelFile},
+		OnStrategy:           func(s Strategy, err error) { events = append(events, fmt.Sprintf("%s:%v", s, err != nil)) },
+		SentinelFile:         sentinel,
+		AppExitStatus:        3,
+		DisableDepthWarnings: true,
+	}
+
+	func() {
+		defer func() { recover() }()
+		ExitWithDirectoryAdvanced(target, opts)
+	}()
+
+	if exitCode != 3 {
+		t.Errorf("Expected exit with the application status 3, got %d", exitCode)
+	}
+	if got, _ := os.ReadFile(sentinel); string(got) != target {
+		t.Errorf("Expected the sentinel file to hold %s, got %q", target, got)
+	}
+	if fmt.Sprint(events) != "[tmux:true sentinel-file:false]" {
+		t.Errorf("Unexpected strategy events: %v", events)
+	}
+
+	// An invalid target ends the walk at the first path error
+	events = nil
+	err := ExitWithDirectoryAdvanced(filepath.Join(target, "missing"), opts)
+	if !IsPathError(err) {
+		t.Errorf("Expected a path error, got: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected the walk to stop after one strategy, got %v", events)
+	}
+}
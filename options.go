@@ -0,0 +1,43 @@
+package autocd
+
+// Option configures a transition for ExitWithDirectoryOpts
+type Option func(*Options)
+
+// WithSecurityLevel sets the path validation strictness
+func WithSecurityLevel(level SecurityLevel) Option {
+	return func(o *Options) { o.SecurityLevel = level }
+}
+
+// WithShell overrides shell detection ("bash", "zsh", "bash --noprofile -i", ...)
+func WithShell(shell string) Option {
+	return func(o *Options) { o.Shell = shell }
+}
+
+// WithTempDir sets the directory transition scripts are written to
+func WithTempDir(dir string) Option {
+	return func(o *Options) { o.TempDir = dir }
+}
+
+// WithDebug enables or disables verbose logging to stderr
+func WithDebug(enabled bool) Option {
+	return func(o *Options) { o.DebugMode = enabled }
+}
+
+// newOptions applies options on top of the defaults used by ExitWithDirectory
+func newOptions(opts ...Option) *Options {
+	o := withDefaults(nil)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ExitWithDirectoryOpts is ExitWithDirectoryAdvanced configured through
+// functional options instead of an Options struct
+//
+// Example:
+//
+//	err := autocd.ExitWithDirectoryOpts(dir, autocd.WithSecurityLevel(autocd.SecurityStrict), autocd.WithShell("zsh"))
+func ExitWithDirectoryOpts(targetPath string, opts ...Option) error {
+	return exitWithDirectory(targetPath, newOptions(opts...), nil)
+}
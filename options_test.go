@@ -0,0 +1,23 @@
+package autocd
+
+import (
+	"os"
+	"testing"
+)
+
+// Test that functional options start from the ExitWithDirectory defaults
+func TestNewOptions(t *testing.T) {
+	original, had := os.LookupEnv("AUTOCD_DEBUG")
+	defer restoreEnv("AUTOCD_DEBUG", original, had)
+	os.Unsetenv("AUTOCD_DEBUG")
+
+	defaults := newOptions()
+	if defaults.SecurityLevel != SecurityNormal || defaults.DebugMode || defaults.DepthWarningThreshold != defaultDepthWarningThreshold() {
+		t.Errorf("Unexpected defaults: %+v", defaults)
+	}
+
+	opts := newOptions(WithSecurityLevel(SecurityStrict), WithShell("zsh"), WithTempDir("/var/tmp"), WithDebug(true))
+	if opts.SecurityLevel != SecurityStrict || opts.Shell != "zsh" || opts.TempDir != "/var/tmp" || !opts.DebugMode {
+		t.Errorf("Options were not applied: %+v", opts)
+	}
+}
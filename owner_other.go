@@ -0,0 +1,10 @@
+//go:build !unix
+
+package autocd
+
+import "io/fs"
+
+// fileOwner is unknown where files carry no Unix owner IDs
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	return -1, -1, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package autocd
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner returns the owning user and group IDs recorded in info
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	sys, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int(sys.Uid), int(sys.Gid), true
+}
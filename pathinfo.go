@@ -0,0 +1,72 @@
+package autocd
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// PathInfo describes a candidate directory for display in application UIs
+type PathInfo struct {
+	AbsPath        string      // Absolute, cleaned path
+	RealPath       string      // Path with symlinks resolved ("" if unresolvable)
+	Exists         bool        // Whether the path exists
+	IsDir          bool        // Whether the path is a directory
+	Owner          string      // Owning user name (numeric UID if unknown)
+	UID            int         // Owning user ID (-1 if unknown)
+	GID            int         // Owning group ID (-1 if unknown)
+	Mode           fs.FileMode // File mode and permission bits
+	FilesystemType string      // Filesystem type, e.g. "ext4", "nfs", "apfs" ("" if unknown)
+	Writable       bool        // Whether the current user can create files in it
+	Executable     bool        // Whether the current user can cd into it
+}
+
+// ValidateDirectoryEx validates a directory like ValidateDirectory and also
+// returns whatever could be learned about it, so applications can explain
+// why a directory can't be used. The PathInfo is non-nil even on error.
+func ValidateDirectoryEx(targetPath string, securityLevel SecurityLevel) (*PathInfo, error) {
+	info := inspectPath(targetPath)
+	if err := ValidateDirectory(targetPath, securityLevel); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// inspectPath gathers best-effort metadata about a path
+func inspectPath(path string) *PathInfo {
+	info := &PathInfo{UID: -1, GID: -1}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		info.AbsPath = path
+		return info
+	}
+	info.AbsPath = absPath
+
+	if real, err := filepath.EvalSymlinks(absPath); err == nil {
+		info.RealPath = real
+	}
+
+	stat, err := os.Stat(absPath)
+	if err != nil {
+		return info
+	}
+	info.Exists = true
+	info.IsDir = stat.IsDir()
+	info.Mode = stat.Mode()
+
+	if uid, gid, ok := fileOwner(stat); ok {
+		info.UID, info.GID = uid, gid
+		info.Owner = strconv.Itoa(info.UID)
+		if u, err := user.LookupId(info.Owner); err == nil {
+			info.Owner = u.Username
+		}
+	}
+	info.Writable = checkAccess(absPath, accessWrite) == nil
+	info.Executable = checkAccess(absPath, accessExecute) == nil
+
+	info.FilesystemType = filesystemType(absPath)
+	return info
+}
@@ -0,0 +1,54 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test PathInfo for a usable directory
+func TestValidateDirectoryEx_ValidDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	info, err := ValidateDirectoryEx(dir, SecurityNormal)
+	if err != nil {
+		t.Fatalf("ValidateDirectoryEx failed: %v", err)
+	}
+	if info.AbsPath != dir || !info.Exists || !info.IsDir {
+		t.Errorf("Unexpected path info: %+v", info)
+	}
+	if info.RealPath == "" {
+		t.Error("RealPath should be resolved for an existing directory")
+	}
+	if info.UID != os.Geteuid() || info.Owner == "" {
+		t.Errorf("Expected directory owned by the current user, got UID %d owner %q", info.UID, info.Owner)
+	}
+	if !info.Writable || !info.Executable {
+		t.Errorf("Temp directory should be writable and executable: %+v", info)
+	}
+}
+
+// Test that PathInfo is returned alongside validation errors
+func TestValidateDirectoryEx_Errors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(file, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	info, err := ValidateDirectoryEx(file, SecurityNormal)
+	if !errors.Is(err, ErrPathNotDirectory) {
+		t.Errorf("Expected ErrPathNotDirectory, got: %v", err)
+	}
+	if info == nil || !info.Exists || info.IsDir {
+		t.Errorf("Expected info describing an existing file, got %+v", info)
+	}
+
+	info, err = ValidateDirectoryEx("/nonexistent/autocd/path", SecurityNormal)
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got: %v", err)
+	}
+	if info == nil || info.Exists || info.UID != -1 {
+		t.Errorf("Expected empty info for a missing path, got %+v", info)
+	}
+}
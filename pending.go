@@ -0,0 +1,50 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// BufferedOutput is a writer holding data until flushed, such as a
+// *bufio.Writer; exec discards whatever it still buffers
+type BufferedOutput interface {
+	Buffered() int
+}
+
+// checkPendingWork looks for work exec would silently discard: goroutines
+// besides the caller (own counts goroutines autocd itself left running)
+// and unflushed PendingOutputs. It warns in debug mode and fails with
+// ErrPendingWork under RefusePendingWork.
+func checkPendingWork(opts *Options, own int) error {
+	if !opts.DebugMode && !opts.RefusePendingWork {
+		return nil
+	}
+
+	problems := pendingWork(runtime.NumGoroutine()-own, opts.PendingOutputs)
+	if len(problems) == 0 {
+		return nil
+	}
+	summary := strings.Join(problems, "; ")
+	if opts.RefusePendingWork {
+		return fmt.Errorf("%w: %s", ErrPendingWork, summary)
+	}
+	fmt.Fprintf(os.Stderr, "autocd: warning: exec will discard %s\n", summary)
+	return nil
+}
+
+// pendingWork describes the goroutines beyond the calling one and the
+// outputs with buffered data
+func pendingWork(goroutines int, outputs []BufferedOutput) []string {
+	var problems []string
+	if goroutines > 1 {
+		problems = append(problems, fmt.Sprintf("%d running goroutines besides the caller", goroutines-1))
+	}
+	for i, out := range outputs {
+		if n := out.Buffered(); n > 0 {
+			problems = append(problems, fmt.Sprintf("%d unflushed bytes in pending output %d", n, i))
+		}
+	}
+	return problems
+}
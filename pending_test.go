@@ -0,0 +1,47 @@
+package autocd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Test detection of goroutines and unflushed output exec would discard
+func TestPendingWork(t *testing.T) {
+	if problems := pendingWork(1, nil); len(problems) != 0 {
+		t.Errorf("A lone caller goroutine is not pending work: %v", problems)
+	}
+
+	buffered := bufio.NewWriter(io.Discard)
+	buffered.WriteString("last log line\n")
+	flushed := bufio.NewWriter(io.Discard)
+
+	problems := pendingWork(3, []BufferedOutput{flushed, buffered})
+	joined := strings.Join(problems, "; ")
+	if len(problems) != 2 || !strings.Contains(joined, "2 running goroutines") || !strings.Contains(joined, "14 unflushed bytes in pending output 1") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
+// Test that pending work is only checked in debug or strict mode
+func TestCheckPendingWork(t *testing.T) {
+	buffered := bufio.NewWriter(io.Discard)
+	buffered.WriteString("pending")
+
+	if err := checkPendingWork(&Options{PendingOutputs: []BufferedOutput{buffered}}, 0); err != nil {
+		t.Errorf("Pending work should be ignored by default, got: %v", err)
+	}
+
+	err := checkPendingWork(&Options{PendingOutputs: []BufferedOutput{buffered}, RefusePendingWork: true}, 0)
+	if !errors.Is(err, ErrPendingWork) {
+		t.Errorf("Expected ErrPendingWork, got: %v", err)
+	}
+
+	buffered.Flush()
+	opts := &Options{PendingOutputs: []BufferedOutput{buffered}, RefusePendingWork: true}
+	if err := checkPendingWork(opts, 0); err != nil && !strings.Contains(err.Error(), "goroutines") {
+		t.Errorf("Flushed output should not be reported, got: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package autocd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hostPolicyFile is the host-wide path policy enforced by every
+// autocd-enabled tool when present (replaceable in tests)
+var hostPolicyFile = "/etc/autocd/policy"
+
+// pathPolicy holds the rules of a policy file. The format is one rule per
+// line, '#' starting a comment:
+//
+//	allow glob /home/*          # target must be under a matching directory
+//	deny  regex ^/home/[^/]+/\.ssh(/|$)
+//	owner user                  # target owned by the real user ("root", a name or a uid also work)
+//	max-depth 8                 # at most 8 path components
+//
+// Deny rules win over allow rules; when any allow rule exists, targets
+// must match one. A glob matches a directory and everything below it.
+type pathPolicy struct {
+	allow    []policyMatcher
+	deny     []policyMatcher
+	owners   []int
+	maxDepth int // 0 = unlimited
+}
+
+// policyMatcher is one allow or deny pattern
+type policyMatcher struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// matches reports whether path (or, for globs, one of its parents) matches
+func (m policyMatcher) matches(path string) bool {
+	if m.regex != nil {
+		return m.regex.MatchString(path)
+	}
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if ok, _ := filepath.Match(m.glob, dir); ok {
+			return true
+		}
+		if dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// loadPathPolicy reads a policy file. A missing file yields a nil policy
+// unless required; a malformed file is an error so policies fail closed.
+func loadPathPolicy(path string, required bool) (*pathPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: cannot read policy %s: %v", ErrPolicyViolation, path, err)
+	}
+	defer f.Close()
+
+	policy := &pathPolicy{}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := policy.addRule(fields); err != nil {
+			return nil, fmt.Errorf("%w: %s:%d: %v", ErrPolicyViolation, path, lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: cannot read policy %s: %v", ErrPolicyViolation, path, err)
+	}
+	return policy, nil
+}
+
+// addRule parses one policy line split into fields
+func (p *pathPolicy) addRule(fields []string) error {
+	switch fields[0] {
+	case "allow", "deny":
+		if len(fields) != 3 {
+			return fmt.Errorf("expected %q glob|regex PATTERN", fields[0])
+		}
+		var m policyMatcher
+		switch fields[1] {
+		case "glob":
+			if _, err := filepath.Match(fields[2], "/"); err != nil {
+				return fmt.Errorf("invalid glob %q: %v", fields[2], err)
+			}
+			m.glob = fields[2]
+		case "regex":
+			re, err := regexp.Compile(fields[2])
+			if err != nil {
+				return fmt.Errorf("invalid regex %q: %v", fields[2], err)
+			}
+			m.regex = re
+		default:
+			return fmt.Errorf("unknown pattern type %q", fields[1])
+		}
+		if fields[0] == "allow" {
+			p.allow = append(p.allow, m)
+		} else {
+			p.deny = append(p.deny, m)
+		}
+	case "owner":
+		if len(fields) != 2 {
+			return fmt.Errorf("expected owner user|root|NAME|UID")
+		}
+		uid, err := policyOwnerUID(fields[1])
+		if err != nil {
+			return err
+		}
+		p.owners = append(p.owners, uid)
+	case "max-depth":
+		if len(fields) != 2 {
+			return fmt.Errorf("expected max-depth N")
+		}
+		depth, err := strconv.Atoi(fields[1])
+		if err != nil || depth <= 0 {
+			return fmt.Errorf("invalid max-depth %q", fields[1])
+		}
+		p.maxDepth = depth
+	default:
+		return fmt.Errorf("unknown rule %q", fields[0])
+	}
+	return nil
+}
+
+// policyOwnerUID resolves an owner rule value to a uid
+func policyOwnerUID(owner string) (int, error) {
+	switch owner {
+	case "user":
+		return os.Getuid(), nil
+	case "root":
+		return 0, nil
+	}
+	if uid, err := strconv.Atoi(owner); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, fmt.Errorf("unknown owner %q", owner)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// check enforces the policy on a cleaned absolute path
+func (p *pathPolicy) check(absPath string) error {
+	for _, m := range p.deny {
+		if m.matches(absPath) {
+			return fmt.Errorf("%w: %s matches a deny rule", ErrPolicyViolation, absPath)
+		}
+	}
+
+	if len(p.allow) > 0 {
+		allowed := false
+		for _, m := range p.allow {
+			if m.matches(absPath) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s matches no allow rule", ErrPolicyViolation, absPath)
+		}
+	}
+
+	if p.maxDepth > 0 {
+		if depth := pathDepth(absPath); depth > p.maxDepth {
+			return fmt.Errorf("%w: %s is %d levels deep (max %d)", ErrPolicyViolation, absPath, depth, p.maxDepth)
+		}
+	}
+
+	if len(p.owners) > 0 {
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return fmt.Errorf("%w: cannot check owner of %s: %v", ErrPolicyViolation, absPath, err)
+		}
+		uid, _, ok := fileOwner(info)
+		if !ok || !containsUID(p.owners, uid) {
+			return fmt.Errorf("%w: %s is not owned by an allowed user", ErrPolicyViolation, absPath)
+		}
+	}
+	return nil
+}
+
+// pathDepth counts the components of an absolute path ("/" is 0)
+func pathDepth(absPath string) int {
+	trimmed := strings.Trim(filepath.ToSlash(absPath), "/")
+	if trimmed == "" {
+		return 0
+	}
+	return strings.Count(trimmed, "/") + 1
+}
+
+// containsUID reports whether uid is listed
+func containsUID(uids []int, uid int) bool {
+	for _, u := range uids {
+		if u == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// enforcePolicyFile checks absPath against the policy in path. Only a
+// required policy must exist. Symbolic links are resolved and both forms
+// must pass, so a link cannot smuggle a target out of a denied tree.
+func enforcePolicyFile(path, absPath string, required bool) error {
+	policy, err := loadPathPolicy(path, required)
+	if err != nil || policy == nil {
+		return err
+	}
+	if err := policy.check(absPath); err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil && resolved != absPath {
+		return policy.check(resolved)
+	}
+	return nil
+}
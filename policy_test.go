@@ -0,0 +1,127 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePolicy stores policy rules in a temporary file
+func writePolicy(t *testing.T, rules string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy")
+	if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	return path
+}
+
+// Test allow/deny globs and regexes
+func TestPathPolicy_AllowDeny(t *testing.T) {
+	base := t.TempDir()
+	allowed := filepath.Join(base, "projects", "app")
+	secret := filepath.Join(base, "projects", ".secrets")
+	outside := t.TempDir()
+	for _, dir := range []string{allowed, secret} {
+		os.MkdirAll(dir, 0755)
+	}
+
+	policy := writePolicy(t, "# test policy\n"+
+		"allow glob "+filepath.Join(base, "projects")+"\n"+
+		"deny regex /\\.secrets(/|$)  # never\n")
+
+	if err := enforcePolicyFile(policy, allowed, true); err != nil {
+		t.Errorf("Directory below an allowed glob should pass: %v", err)
+	}
+	if err := enforcePolicyFile(policy, secret, true); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Denied directory should fail with ErrPolicyViolation, got: %v", err)
+	}
+	if err := enforcePolicyFile(policy, outside, true); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Directory outside the allow rules should fail, got: %v", err)
+	}
+}
+
+// Test that a symbolic link into a denied tree is denied
+func TestPathPolicy_SymlinkIntoDeniedTree(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.Mkdir(secret, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	link := filepath.Join(t.TempDir(), "x")
+	if err := os.Symlink(secret, link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(secret)
+
+	policy := writePolicy(t, "deny glob "+resolved+"\n")
+	if err := enforcePolicyFile(policy, link, true); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Symlink into a denied tree should fail, got: %v", err)
+	}
+
+	policy = writePolicy(t, "deny glob "+link+"\n")
+	if err := enforcePolicyFile(policy, link, true); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Denied link path should still fail, got: %v", err)
+	}
+	if err := enforcePolicyFile(policy, resolved, true); err != nil {
+		t.Errorf("The link target itself is not denied: %v", err)
+	}
+}
+
+// Test owner and depth rules
+func TestPathPolicy_OwnerAndDepth(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := enforcePolicyFile(writePolicy(t, "owner user\n"), dir, true); err != nil {
+		t.Errorf("Directory owned by the user should pass: %v", err)
+	}
+	if os.Getuid() != 0 {
+		if err := enforcePolicyFile(writePolicy(t, "owner root\n"), dir, true); !errors.Is(err, ErrPolicyViolation) {
+			t.Errorf("Directory not owned by root should fail, got: %v", err)
+		}
+	}
+
+	if err := enforcePolicyFile(writePolicy(t, "max-depth 1\n"), dir, true); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Deep directory should fail max-depth, got: %v", err)
+	}
+	if depth := pathDepth("/"); depth != 0 {
+		t.Errorf("Root depth should be 0, got %d", depth)
+	}
+	if depth := pathDepth("/a/b/c"); depth != 3 {
+		t.Errorf("Expected depth 3, got %d", depth)
+	}
+}
+
+// Test that missing and malformed policies are handled safely
+func TestLoadPathPolicy_Errors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	if policy, err := loadPathPolicy(missing, false); policy != nil || err != nil {
+		t.Errorf("Optional missing policy should be ignored, got %v, %v", policy, err)
+	}
+	if _, err := loadPathPolicy(missing, true); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Required missing policy should fail, got: %v", err)
+	}
+
+	for _, rules := range []string{"allow glob\n", "deny regex (\n", "allow path /tmp\n", "max-depth -1\n", "owner no-such-user-xyz\n", "frobnicate\n"} {
+		if _, err := loadPathPolicy(writePolicy(t, rules), false); !errors.Is(err, ErrPolicyViolation) {
+			t.Errorf("Malformed policy %q should fail closed, got: %v", rules, err)
+		}
+	}
+}
+
+// Test that the host policy is enforced by validateTargetPath
+func TestValidateTargetPath_HostPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	original := hostPolicyFile
+	hostPolicyFile = writePolicy(t, "deny glob "+dir+"\n")
+	defer func() { hostPolicyFile = original }()
+
+	_, err := validateTargetPath(dir, SecurityNormal)
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("Expected ErrPolicyViolation, got: %v", err)
+	}
+	if autoErr := newPathValidationError(dir, err); autoErr.Type != ErrorSecurityViolation {
+		t.Errorf("Policy violations should be security errors, got type %v", autoErr.Type)
+	}
+}
@@ -0,0 +1,97 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// preflightScript is the no-op script run to test a location
+const preflightScript = "#!/bin/sh\nexit 0\n"
+
+// pipedScriptLimit bounds scripts passed through a pipe; the whole script
+// must fit in the pipe buffer because nothing reads it before exec
+const pipedScriptLimit = 16 * 1024
+
+// preflightResults caches the outcome of probing each directory, so the
+// test runs at most once per process and location
+var preflightResults = struct {
+	sync.Mutex
+	byDir map[string]error
+}{byDir: map[string]error{}}
+
+// preflightScriptDir writes a no-op script in dir and runs it the way the
+// real script is run, through /bin/sh, which catches SELinux or AppArmor
+// denials before the real script is committed there. Results are cached.
+func preflightScriptDir(dir string) error {
+	preflightResults.Lock()
+	defer preflightResults.Unlock()
+
+	if err, done := preflightResults.byDir[dir]; done {
+		return err
+	}
+	err := runPreflightScript(dir)
+	preflightResults.byDir[dir] = err
+	return err
+}
+
+// runPreflightScript performs one uncached probe of dir
+func runPreflightScript(dir string) error {
+	scriptPath, err := createTemporaryScript(preflightScript, ".sh", dir, "")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(scriptPath)
+
+	if output, err := exec.Command("/bin/sh", scriptPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("test script in %s cannot run: %w: %s", dir, explainExecError("/bin/sh", err), output)
+	}
+	return nil
+}
+
+// scriptDirCandidates lists the locations tried for scripts, in order: the
+// configured temp directory, the system one, then XDG_RUNTIME_DIR
+func scriptDirCandidates(opts *Options) []string {
+	candidates := []string{GetTempDir(opts.TempDir), systemTempDir()}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" && DirectoryExists(runtimeDir) {
+		candidates = append(candidates, runtimeDir)
+	}
+	return candidates
+}
+
+// firstRunnableDir returns the first directory passing the preflight test
+func firstRunnableDir(dirs []string, debugMode bool) (string, error) {
+	var lastErr error
+	for _, dir := range dirs {
+		lastErr = preflightScriptDir(dir)
+		if lastErr == nil {
+			return dir, nil
+		}
+		if debugMode {
+			fmt.Fprintf(os.Stderr, "autocd: preflight: %v\n", lastErr)
+		}
+	}
+	return "", lastErr
+}
+
+// writePipedScript passes the script through a pipe instead of a file and
+// returns a /dev/fd path to its read end, handed to /bin/sh at exec
+func writePipedScript(content string) (string, func(), error) {
+	if len(content) > pipedScriptLimit {
+		return "", nil, fmt.Errorf("script of %d bytes is too large for a pipe", len(content))
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create script pipe: %w", err)
+	}
+	_, err = w.WriteString(content)
+	w.Close()
+	if err != nil {
+		r.Close()
+		return "", nil, fmt.Errorf("failed to write script pipe: %w", err)
+	}
+
+	return scriptFDPath(r)
+}
@@ -0,0 +1,128 @@
+package autocd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test probing script locations and falling back past unusable ones
+func TestFirstRunnableDir(t *testing.T) {
+	good := t.TempDir()
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	dir, err := firstRunnableDir([]string{missing, good}, false)
+	if err != nil || dir != good {
+		t.Fatalf("Expected %s, got %q (%v)", good, dir, err)
+	}
+	if _, err := firstRunnableDir([]string{missing}, false); err == nil {
+		t.Error("Expected an error when no location can run scripts")
+	}
+
+	// The result is cached, so the probe does not run again
+	if err := os.Chmod(good, 0500); err != nil {
+		t.Fatalf("chmod failed: %v", err)
+	}
+	defer os.Chmod(good, 0700)
+	if err := preflightScriptDir(good); err != nil {
+		t.Errorf("Expected the cached result, got: %v", err)
+	}
+
+	entries, _ := os.ReadDir(good)
+	if len(entries) != 0 {
+		t.Errorf("The test script should be removed, found %d entries", len(entries))
+	}
+}
+
+// Test the in-memory script passed to /bin/sh through a pipe
+func TestWritePipedScript(t *testing.T) {
+	path, release, err := writePipedScript("echo piped\n")
+	if err != nil {
+		t.Fatalf("writePipedScript failed: %v", err)
+	}
+	defer release()
+
+	// Children started before the exec must not inherit the descriptor
+	leak := exec.Command("/bin/sh", "-c", "cat <&"+strings.TrimPrefix(path, "/dev/fd/"))
+	if output, err := leak.Output(); err == nil || len(output) != 0 {
+		t.Errorf("The script descriptor leaked into a child: %q", output)
+	}
+
+	cmd, closeScript, err := scriptCommand(path)
+	if err != nil {
+		t.Fatalf("scriptCommand failed: %v", err)
+	}
+	defer closeScript()
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sh failed: %v", err)
+	}
+	if string(output) != "piped\n" {
+		t.Errorf("Unexpected output %q", output)
+	}
+
+	if _, _, err := writePipedScript(string(make([]byte, pipedScriptLimit+1))); err == nil {
+		t.Error("Expected oversized scripts to be refused")
+	}
+}
+
+// Test that the shell does not inherit the descriptor the script came through
+func TestWritePipedScript_ClosesDescriptor(t *testing.T) {
+	fake := filepath.Join(t.TempDir(), "sh")
+	probe := "#!/bin/sh\nif (: <&\"$AUTOCD_TEST_FD\") 2>/dev/null; then echo leaked; else echo closed; fi\n"
+	if err := os.WriteFile(fake, []byte(probe), 0755); err != nil {
+		t.Fatalf("Failed to write fake shell: %v", err)
+	}
+	script, err := generateScript(t.TempDir(), &ShellInfo{Path: fake, IsValid: true}, &Options{PlainOutput: true}, nil)
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+
+	path, release, err := writePipedScript(script)
+	if err != nil {
+		t.Fatalf("writePipedScript failed: %v", err)
+	}
+	defer release()
+
+	cmd, closeScript, err := scriptCommand(path)
+	if err != nil {
+		t.Fatalf("scriptCommand failed: %v", err)
+	}
+	defer closeScript()
+	cmd.Env = append(os.Environ(), "AUTOCD_TEST_FD=3")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if !strings.HasSuffix(string(output), "closed\n") {
+		t.Errorf("Expected the script descriptor to be closed in the shell, got:\n%s", output)
+	}
+}
+
+// Test that the descriptor is made inheritable only for the exec
+func TestInheritScriptFD(t *testing.T) {
+	path, release, err := writePipedScript("echo inherited\n")
+	if err != nil {
+		t.Fatalf("writePipedScript failed: %v", err)
+	}
+	defer release()
+
+	inherited, releaseFD, err := inheritScriptFD(path)
+	if err != nil {
+		t.Fatalf("inheritScriptFD failed: %v", err)
+	}
+	defer releaseFD()
+	if inherited == path {
+		t.Fatalf("Expected a duplicate descriptor, got %s", inherited)
+	}
+	output, err := exec.Command("/bin/sh", inherited).Output()
+	if err != nil || string(output) != "inherited\n" {
+		t.Errorf("Expected /bin/sh to read the duplicate, got %q (%v)", output, err)
+	}
+
+	if plain, _, _ := inheritScriptFD("/tmp/autocd_1_2_x.sh"); plain != "/tmp/autocd_1_2_x.sh" {
+		t.Errorf("File paths should be returned unchanged, got %s", plain)
+	}
+}
@@ -0,0 +1,75 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// isPrivileged reports whether the process runs setuid or setgid, i.e. its
+// effective IDs differ from the real IDs of the user who started it
+var isPrivileged = func() bool {
+	return os.Geteuid() != os.Getuid() || os.Getegid() != os.Getgid()
+}
+
+// privilegedTempDir returns a private script directory for setuid/setgid
+// processes. TMPDIR is controlled by the invoking user and cannot be trusted,
+// so the directory lives under /tmp and must be owned by the effective user
+// with no group or other access.
+func privilegedTempDir(customTempDir string) (string, error) {
+	if customTempDir != "" {
+		if err := checkPrivateDir(customTempDir); err != nil {
+			return "", err
+		}
+		return customTempDir, nil
+	}
+
+	dir := filepath.Join("/tmp", "autocd-"+strconv.Itoa(os.Geteuid()))
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("%w: cannot create private temp dir: %v", ErrUnsafePrivileges, err)
+	}
+	if err := checkPrivateDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// checkPrivateDir verifies a directory is a real directory (not a symlink)
+// owned by the effective user and inaccessible to group and others
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsafePrivileges, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrUnsafePrivileges, dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%w: %s is accessible to other users (mode %v)", ErrUnsafePrivileges, dir, info.Mode().Perm())
+	}
+	if uid, _, ok := fileOwner(info); ok && uid != os.Geteuid() {
+		return fmt.Errorf("%w: %s is not owned by uid %d", ErrUnsafePrivileges, dir, os.Geteuid())
+	}
+	return nil
+}
+
+// checkScriptOwnership verifies the script about to be executed is a regular
+// file owned by the effective user and not writable by anyone else, so it
+// cannot have been swapped between creation and exec
+func checkScriptOwnership(scriptPath string) error {
+	info, err := os.Lstat(scriptPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsafePrivileges, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: script is not a regular file", ErrUnsafePrivileges)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%w: script is writable by other users", ErrUnsafePrivileges)
+	}
+	if uid, _, ok := fileOwner(info); ok && uid != os.Geteuid() {
+		return fmt.Errorf("%w: script is not owned by uid %d", ErrUnsafePrivileges, os.Geteuid())
+	}
+	return nil
+}
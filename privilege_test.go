@@ -0,0 +1,94 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// Test private directory requirements used for setuid/setgid processes
+func TestCheckPrivateDir(t *testing.T) {
+	base := t.TempDir()
+
+	private := filepath.Join(base, "private")
+	shared := filepath.Join(base, "shared")
+	link := filepath.Join(base, "link")
+	os.Mkdir(private, 0700)
+	os.Mkdir(shared, 0700)
+	os.Chmod(shared, 0777)
+	os.Symlink(private, link)
+
+	if err := checkPrivateDir(private); err != nil {
+		t.Errorf("Private directory should pass: %v", err)
+	}
+	if err := checkPrivateDir(shared); !errors.Is(err, ErrUnsafePrivileges) {
+		t.Errorf("World-writable directory should fail with ErrUnsafePrivileges, got: %v", err)
+	}
+	if err := checkPrivateDir(link); !errors.Is(err, ErrUnsafePrivileges) {
+		t.Errorf("Symlinked directory should fail with ErrUnsafePrivileges, got: %v", err)
+	}
+}
+
+// Test script ownership verification before exec
+func TestCheckScriptOwnership(t *testing.T) {
+	dir := t.TempDir()
+
+	script := filepath.Join(dir, "script.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\n"), 0700)
+	if err := checkScriptOwnership(script); err != nil {
+		t.Errorf("Private script should pass: %v", err)
+	}
+
+	os.Chmod(script, 0777)
+	if err := checkScriptOwnership(script); !errors.Is(err, ErrUnsafePrivileges) {
+		t.Errorf("World-writable script should fail, got: %v", err)
+	}
+	if !errors.Is(ErrUnsafePrivileges, ErrSecurityViolation) {
+		t.Error("ErrUnsafePrivileges should be a security violation")
+	}
+}
+
+// Test that RefuseAsRoot declines with a recoverable error
+func TestExitWithDirectoryAdvanced_RefuseAsRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires running as root")
+	}
+
+	err := ExitWithDirectoryAdvanced(t.TempDir(), &Options{RefuseAsRoot: true, DisableDepthWarnings: true})
+	if !errors.Is(err, ErrRunningAsRoot) {
+		t.Fatalf("Expected ErrRunningAsRoot, got: %v", err)
+	}
+
+	var autoErr *AutoCDError
+	if !errors.As(err, &autoErr) || !autoErr.IsRecoverable() {
+		t.Error("ErrRunningAsRoot should be a recoverable AutoCDError")
+	}
+}
+
+// Test that the private temp dir of a privileged process does not leak
+// into the caller's Options
+func TestPrepareTransition_PrivilegedKeepsOptions(t *testing.T) {
+	privateDir := filepath.Join("/tmp", "autocd-"+strconv.Itoa(os.Geteuid()))
+	if _, err := os.Lstat(privateDir); os.IsNotExist(err) {
+		t.Cleanup(func() { os.RemoveAll(privateDir) })
+	}
+	saved := isPrivileged
+	isPrivileged = func() bool { return true }
+	t.Cleanup(func() { isPrivileged = saved })
+
+	opts := &Options{SkipTTYCheck: true, DisableDepthWarnings: true}
+	cmd, cleanup, err := BuildCommand(t.TempDir(), opts)
+	if err != nil {
+		t.Fatalf("BuildCommand failed: %v", err)
+	}
+	defer cleanup()
+
+	if opts.TempDir != "" {
+		t.Errorf("The caller's TempDir was changed to %q", opts.TempDir)
+	}
+	if script := cmd.Args[len(cmd.Args)-1]; filepath.Dir(script) != privateDir {
+		t.Errorf("Expected the script in %s, got %s", privateDir, script)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package autocd
+
+import "os/exec"
+
+// detachFromTerminal has no sessions to create on this platform
+func detachFromTerminal(cmd *exec.Cmd) {}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package autocd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachFromTerminal starts cmd in a new session, so it has no controlling
+// terminal it could open or take the foreground group of
+func detachFromTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
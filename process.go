@@ -0,0 +1,50 @@
+package autocd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scriptOwnerPID extracts the PID stamped into a script name of the form
+// autocd_<pid>_<unix-seconds>_<app>-<random><ext> (or the older
+// autocd_<pid>_<random><ext>). Names without a PID stamp return false.
+func scriptOwnerPID(name string) (int, bool) {
+	rest := strings.TrimPrefix(name, "autocd_")
+	if rest == name {
+		return 0, false
+	}
+
+	end := strings.IndexByte(rest, '_')
+	if end <= 0 {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(rest[:end])
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
+
+// scriptNameInfo extracts the PID and creation time encoded in a script
+// name of the form autocd_<pid>_<unix-seconds>_<random><ext>, so cleanup can
+// classify entries without statting them. Older names return false.
+func scriptNameInfo(name string) (int, time.Time, bool) {
+	pid, ok := scriptOwnerPID(name)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+
+	rest := strings.TrimPrefix(name, "autocd_"+strconv.Itoa(pid)+"_")
+	end := strings.IndexByte(rest, '_')
+	if end <= 0 {
+		return 0, time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseInt(rest[:end], 10, 64)
+	if err != nil || seconds <= 0 {
+		return 0, time.Time{}, false
+	}
+	return pid, time.Unix(seconds, 0), true
+}
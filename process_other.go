@@ -0,0 +1,21 @@
+//go:build !unix
+
+package autocd
+
+import "os"
+
+// processAlive reports whether a process with the given PID currently
+// exists. Where signal 0 is unavailable, finding the process stands in;
+// platforms that cannot tell report every PID alive, so cleanup keeps the
+// script.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
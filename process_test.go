@@ -0,0 +1,189 @@
+package autocd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Test PID extraction from script names
+func TestScriptOwnerPID(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantPID int
+		wantOK  bool
+	}{
+		{"autocd_1234_987654.sh", 1234, true},
+		{"autocd_42_abc", 42, true},
+		{"autocd_old.sh", 0, false},
+		{"autocd_abc_123.sh", 0, false},
+		{"autocd__123.sh", 0, false},
+		{"other_1234_5.sh", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pid, ok := scriptOwnerPID(tt.name)
+			if pid != tt.wantPID || ok != tt.wantOK {
+				t.Errorf("scriptOwnerPID(%q) = %d, %v; want %d, %v", tt.name, pid, ok, tt.wantPID, tt.wantOK)
+			}
+		})
+	}
+}
+
+// Test that scripts owned by dead processes are reclaimed regardless of age
+func TestCleanupOldScripts_Orphaned(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Obtain the PID of a process that has already exited
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("unable to run helper process: %v", err)
+	}
+	deadPID := cmd.Process.Pid
+	if processAlive(deadPID) {
+		t.Skip("helper PID was reused")
+	}
+
+	orphan := filepath.Join(tempDir, "autocd_"+strconv.Itoa(deadPID)+"_1.sh")
+	live := filepath.Join(tempDir, "autocd_"+strconv.Itoa(os.Getpid())+"_2.sh")
+	for _, file := range []string{orphan, live} {
+		if err := os.WriteFile(file, []byte("test"), 0700); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	if err := cleanupOldScriptsInDir(tempDir, 24*time.Hour); err != nil {
+		t.Fatalf("cleanupOldScriptsInDir failed: %v", err)
+	}
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("Orphaned script should have been deleted")
+	}
+	if _, err := os.Stat(live); err != nil {
+		t.Error("Script owned by a live process should still exist")
+	}
+}
+
+// Test that created scripts carry the current PID
+func TestCreateTemporaryScript_PIDStamp(t *testing.T) {
+	scriptPath, err := createTemporaryScript("test", ".sh", t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("createTemporaryScript failed: %v", err)
+	}
+
+	pid, ok := scriptOwnerPID(filepath.Base(scriptPath))
+	if !ok || pid != os.Getpid() {
+		t.Errorf("Expected script name stamped with PID %d, got %s", os.Getpid(), scriptPath)
+	}
+}
+
+// Test PID and timestamp extraction from script names
+func TestScriptNameInfo(t *testing.T) {
+	pid, created, ok := scriptNameInfo("autocd_1234_1700000000_987654.sh")
+	if !ok || pid != 1234 || created.Unix() != 1700000000 {
+		t.Errorf("Unexpected result: %d, %v, %v", pid, created, ok)
+	}
+
+	for _, name := range []string{"autocd_1234_987654.sh", "autocd_1234_abc_1.sh", "autocd_old.sh"} {
+		if _, _, ok := scriptNameInfo(name); ok {
+			t.Errorf("scriptNameInfo(%q) should not parse", name)
+		}
+	}
+}
+
+// Test that name-encoded age is used without relying on modification times
+func TestCleanupOldScripts_NameEncodedAge(t *testing.T) {
+	tempDir := t.TempDir()
+	pid := strconv.Itoa(os.Getpid())
+
+	old := filepath.Join(tempDir, "autocd_"+pid+"_"+strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)+"_1.sh")
+	fresh := filepath.Join(tempDir, "autocd_"+pid+"_"+strconv.FormatInt(time.Now().Unix(), 10)+"_2.sh")
+	for _, file := range []string{old, fresh} {
+		if err := os.WriteFile(file, []byte("test"), 0700); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	if err := cleanupOldScriptsInDir(tempDir, time.Hour); err != nil {
+		t.Fatalf("cleanupOldScriptsInDir failed: %v", err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Script with an old encoded timestamp should have been deleted")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Error("Fresh script owned by a live process should still exist")
+	}
+}
+
+// Test that cleanup leaves foreign and unexpected files alone
+func TestCleanupOldScripts_OwnershipAndModes(t *testing.T) {
+	tempDir := t.TempDir()
+	oldTime := time.Now().Add(-2 * time.Hour)
+
+	own := filepath.Join(tempDir, "autocd_own.sh")
+	writable := filepath.Join(tempDir, "autocd_writable.sh")
+	setuid := filepath.Join(tempDir, "autocd_setuid.sh")
+	foreign := filepath.Join(tempDir, "autocd_foreign.sh")
+	for _, file := range []string{own, writable, setuid, foreign} {
+		if err := os.WriteFile(file, []byte("test"), 0700); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(file, oldTime, oldTime); err != nil {
+			t.Fatalf("Failed to set file time: %v", err)
+		}
+	}
+	os.Chmod(writable, 0777)
+	os.Chmod(setuid, 0700|os.ModeSetuid)
+	dir := filepath.Join(tempDir, "autocd_dir")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	os.Chtimes(dir, oldTime, oldTime)
+
+	// Only root can hand a file to another user
+	chowned := os.Geteuid() == 0 && os.Chown(foreign, 12345, 12345) == nil
+
+	if err := cleanupOldScriptsInDir(tempDir, time.Hour); err != nil {
+		t.Fatalf("cleanupOldScriptsInDir failed: %v", err)
+	}
+
+	if _, err := os.Stat(own); !os.IsNotExist(err) {
+		t.Error("Old script owned by the current user should have been deleted")
+	}
+	for _, kept := range []string{writable, setuid, dir} {
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("%s has an unexpected mode and should still exist", filepath.Base(kept))
+		}
+	}
+	if _, err := os.Stat(foreign); chowned && err != nil {
+		t.Error("Script owned by another user should still exist")
+	}
+}
+
+// Test reading a process's working directory
+func TestGetProcessCWD(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+
+	got, err := GetProcessCWD(os.Getpid())
+	if err != nil {
+		t.Skipf("process working directory unavailable here: %v", err)
+	}
+	if got != cwd {
+		t.Errorf("Expected %s, got %s", cwd, got)
+	}
+
+	if _, err := GetProcessCWD(0); err == nil {
+		t.Error("Expected an error for pid 0")
+	}
+	if err := ExitWithProcessDirectory(-1); !IsPathError(err) {
+		t.Errorf("Expected a path error for an invalid pid, got: %v", err)
+	}
+}
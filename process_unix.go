@@ -0,0 +1,20 @@
+//go:build unix
+
+package autocd
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID currently exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// Signal 0 performs error checking only; EPERM means the process exists
+	// but belongs to another user
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
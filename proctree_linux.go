@@ -0,0 +1,59 @@
+package autocd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processParent reads the parent PID and command name from /proc/<pid>/stat
+func processParent(pid int) (int, string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Format: pid (comm) state ppid ...; comm may itself contain ") "
+	open := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if open < 0 || end < open {
+		return 0, "", fmt.Errorf("malformed stat for pid %d", pid)
+	}
+
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 2 {
+		return 0, "", fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, "", fmt.Errorf("malformed stat for pid %d: %w", pid, err)
+	}
+	return ppid, string(data[open+1 : end]), nil
+}
+
+// cwdPollable reports that processCWD is cheap enough to poll
+const cwdPollable = true
+
+// processCWD reads the working directory of pid from /proc/<pid>/cwd
+func processCWD(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+}
+
+// isAutocdShell reports whether pid is a shell spawned by autocd. The
+// transition script exports AUTOCD_APP_PID before exec, and exec keeps the
+// PID, so the spawned shell's initial environment names its own PID.
+func isAutocdShell(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		return false
+	}
+	marker := []byte("AUTOCD_APP_PID=" + strconv.Itoa(pid))
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		if bytes.Equal(entry, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+//go:build !linux
+
+package autocd
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// processParent asks ps for the parent PID and command name of pid
+func processParent(pid int) (int, string, error) {
+	out, err := exec.Command("ps", "-o", "ppid=,comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, "", err
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return 0, "", fmt.Errorf("unexpected ps output for pid %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("unexpected ps output for pid %d: %w", pid, err)
+	}
+	return ppid, strings.Join(fields[1:], " "), nil
+}
+
+// cwdPollable is false: spawning lsof is too expensive to poll
+const cwdPollable = false
+
+// processCWD asks lsof for the working directory of pid (libproc would
+// need cgo). lsof -Fn prints one field per line; "n" carries the path.
+func processCWD(pid int) (string, error) {
+	out, err := exec.Command("lsof", "-a", "-p", strconv.Itoa(pid), "-d", "cwd", "-Fn").Output()
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "n") && len(line) > 1 {
+			return line[1:], nil
+		}
+	}
+	return "", fmt.Errorf("unexpected lsof output for pid %d", pid)
+}
+
+// isAutocdShell needs another process's environment, which is not
+// portably readable here; shells are still counted by name
+func isAutocdShell(pid int) bool {
+	return false
+}
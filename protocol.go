@@ -0,0 +1,107 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultCDFileEnv is the variable used by nnn's "cd on quit" protocol
+const DefaultCDFileEnv = "NNN_TMPFILE"
+
+// LastDirPathEnv names the file for the lf/ranger --last-dir-path protocol
+// when Options.LastDirPath is not set
+const LastDirPathEnv = "AUTOCD_LAST_DIR_PATH"
+
+// exitProcess terminates the process (replaceable in tests)
+var exitProcess = os.Exit
+
+// WriteCDFile implements the nnn-style "cd on quit" protocol: when the
+// environment variable envVar ("" = NNN_TMPFILE) names a file, a
+// `cd '<dir>'` command for the validated target is written to it so the
+// user's wrapper function can source it after the application exits.
+//
+// It returns false without error when the variable is not set, letting the
+// caller fall back to ExitWithDirectory.
+func WriteCDFile(targetPath, envVar string, securityLevel SecurityLevel) (bool, error) {
+	validatedPath, err := resolveAndValidate(targetPath, securityLevel)
+	if err != nil {
+		return false, newPathValidationError(targetPath, err)
+	}
+	return writeCDFileFromEnv(validatedPath, envVar)
+}
+
+// writeCDFileFromEnv writes the cd command for an already validated path
+func writeCDFileFromEnv(validatedPath, envVar string) (bool, error) {
+	if envVar == "" {
+		envVar = DefaultCDFileEnv
+	}
+
+	cdFile := os.Getenv(envVar)
+	if cdFile == "" {
+		return false, nil
+	}
+
+	if err := os.WriteFile(cdFile, []byte(cdCommand(validatedPath)), 0600); err != nil {
+		return false, newScriptCreationError(fmt.Errorf("failed to write %s file: %w", envVar, err))
+	}
+	return true, nil
+}
+
+// WriteLastDirFile implements the lf/ranger "--last-dir-path FILE"
+// convention: the plain validated directory is written to file so a wrapper
+// can `cd "$(cat FILE)"` after the application exits.
+func WriteLastDirFile(targetPath, file string, securityLevel SecurityLevel) error {
+	validatedPath, err := resolveAndValidate(targetPath, securityLevel)
+	if err != nil {
+		return newPathValidationError(targetPath, err)
+	}
+	return writeLastDirFile(validatedPath, file)
+}
+
+// writeLastDirFile writes an already validated path without a trailing newline
+func writeLastDirFile(validatedPath, file string) error {
+	if err := os.WriteFile(file, []byte(validatedPath), 0600); err != nil {
+		return newScriptCreationError(fmt.Errorf("failed to write last-dir file: %w", err))
+	}
+	return nil
+}
+
+// lastDirPath returns the configured last-dir file, if any
+func lastDirPath(opts *Options) string {
+	if opts.LastDirPath != "" {
+		return opts.LastDirPath
+	}
+	return os.Getenv(LastDirPathEnv)
+}
+
+// cdCommand returns a POSIX `cd` command line for dir
+func cdCommand(dir string) string {
+	return fmt.Sprintf("cd '%s'\n", sanitizePathForShell(dir))
+}
+
+// writeOutCmd implements the broot-style "--outcmd" protocol: a full shell
+// command (cd plus an optional follow-up) is written to the caller's file or
+// descriptor so a wrapper function can eval it after the application exits.
+// It returns false when neither OutCmdFile nor OutCmdFD is configured.
+func writeOutCmd(validatedPath string, opts *Options) (bool, error) {
+	if opts.OutCmdFile == "" && opts.OutCmdFD <= 0 {
+		return false, nil
+	}
+
+	command := cdCommand(validatedPath)
+	if opts.OutCmdFollowUp != "" {
+		command += opts.OutCmdFollowUp + "\n"
+	}
+
+	if opts.OutCmdFile != "" {
+		if err := os.WriteFile(opts.OutCmdFile, []byte(command), 0600); err != nil {
+			return false, newScriptCreationError(fmt.Errorf("failed to write outcmd file: %w", err))
+		}
+		return true, nil
+	}
+
+	if _, err := writeFD(opts.OutCmdFD, []byte(command)); err != nil {
+		return false, newScriptCreationError(fmt.Errorf("failed to write outcmd descriptor %d: %w", opts.OutCmdFD, err))
+	}
+	return true, nil
+}
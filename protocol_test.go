@@ -0,0 +1,131 @@
+package autocd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test the nnn-style cd file protocol
+func TestWriteCDFile(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "it's here")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	cdFile := filepath.Join(t.TempDir(), "cdfile")
+
+	os.Unsetenv("AUTOCD_TEST_CDFILE")
+	handled, err := WriteCDFile(target, "AUTOCD_TEST_CDFILE", SecurityNormal)
+	if err != nil || handled {
+		t.Fatalf("Expected unhandled without the variable, got %v, %v", handled, err)
+	}
+
+	os.Setenv("AUTOCD_TEST_CDFILE", cdFile)
+	defer os.Unsetenv("AUTOCD_TEST_CDFILE")
+
+	handled, err = WriteCDFile(target, "AUTOCD_TEST_CDFILE", SecurityNormal)
+	if err != nil || !handled {
+		t.Fatalf("Expected handled, got %v, %v", handled, err)
+	}
+
+	content, err := os.ReadFile(cdFile)
+	if err != nil {
+		t.Fatalf("Failed to read cd file: %v", err)
+	}
+	expected := "cd '" + sanitizePathForShell(target) + "'\n"
+	if string(content) != expected {
+		t.Errorf("cd file = %q, want %q", content, expected)
+	}
+}
+
+// Test that ExitWithDirectoryAdvanced exits normally when the protocol applies
+func TestExitWithDirectoryAdvanced_CDFileEnv(t *testing.T) {
+	cdFile := filepath.Join(t.TempDir(), "cdfile")
+	os.Setenv("AUTOCD_TEST_CDFILE", cdFile)
+	defer os.Unsetenv("AUTOCD_TEST_CDFILE")
+
+	exitCode := -1
+	originalExit := exitProcess
+	exitProcess = func(code int) { exitCode = code; panic("exit") }
+	defer func() { exitProcess = originalExit }()
+
+	func() {
+		defer func() { recover() }()
+		ExitWithDirectoryAdvanced(t.TempDir(), &Options{CDFileEnv: "AUTOCD_TEST_CDFILE", AppExitStatus: 4, DisableDepthWarnings: true})
+	}()
+
+	if exitCode != 4 {
+		t.Errorf("Expected exit with the application status 4, got %d", exitCode)
+	}
+	if _, err := os.Stat(cdFile); err != nil {
+		t.Errorf("cd file should have been written: %v", err)
+	}
+}
+
+// Test the lf/ranger last-dir-path protocol
+func TestWriteLastDirFile(t *testing.T) {
+	target := t.TempDir()
+	file := filepath.Join(t.TempDir(), "lastdir")
+
+	if err := WriteLastDirFile(target, file, SecurityNormal); err != nil {
+		t.Fatalf("WriteLastDirFile failed: %v", err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read last-dir file: %v", err)
+	}
+	if string(content) != target {
+		t.Errorf("last-dir file = %q, want %q", content, target)
+	}
+
+	if err := WriteLastDirFile("/nonexistent/autocd/path", file, SecurityNormal); !IsPathError(err) {
+		t.Errorf("Expected path error for invalid target, got: %v", err)
+	}
+}
+
+// Test that the environment variable selects the last-dir mode
+func TestLastDirPath_Env(t *testing.T) {
+	os.Setenv(LastDirPathEnv, "/tmp/from-env")
+	defer os.Unsetenv(LastDirPathEnv)
+
+	if got := lastDirPath(&Options{}); got != "/tmp/from-env" {
+		t.Errorf("Expected path from %s, got %q", LastDirPathEnv, got)
+	}
+	if got := lastDirPath(&Options{LastDirPath: "/tmp/explicit"}); got != "/tmp/explicit" {
+		t.Errorf("Option should take precedence, got %q", got)
+	}
+}
+
+// Test the broot-style outcmd protocol
+func TestWriteOutCmd(t *testing.T) {
+	handled, err := writeOutCmd("/tmp/project", &Options{})
+	if err != nil || handled {
+		t.Fatalf("Expected unhandled without outcmd options, got %v, %v", handled, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "outcmd")
+	handled, err = writeOutCmd("/tmp/project", &Options{OutCmdFile: file, OutCmdFollowUp: "git status"})
+	if err != nil || !handled {
+		t.Fatalf("Expected handled, got %v, %v", handled, err)
+	}
+	content, _ := os.ReadFile(file)
+	if string(content) != "cd '/tmp/project'\ngit status\n" {
+		t.Errorf("Unexpected outcmd file content %q", content)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	handled, err = writeOutCmd("/tmp/project", &Options{OutCmdFD: int(w.Fd())})
+	w.Close()
+	if err != nil || !handled {
+		t.Fatalf("Expected handled for descriptor, got %v, %v", handled, err)
+	}
+	buf := make([]byte, 64)
+	n, _ := r.Read(buf)
+	if string(buf[:n]) != "cd '/tmp/project'\n" {
+		t.Errorf("Unexpected outcmd descriptor content %q", buf[:n])
+	}
+}
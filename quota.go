@@ -0,0 +1,104 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scriptFile is one autocd file counted against the script quota
+type scriptFile struct {
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	Removable bool // Owned by us with the modes autocd uses
+}
+
+// listScripts returns the autocd files in dir, oldest first. The stable
+// ReuseScript files are bounded by design and not counted.
+func listScripts(dir string) ([]scriptFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []scriptFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "autocd_") || strings.HasPrefix(name, "autocd_app-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		scripts = append(scripts, scriptFile{
+			Path:      filepath.Join(dir, name),
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+			Removable: removableScript(info),
+		})
+	}
+
+	sort.Slice(scripts, func(i, j int) bool {
+		return scripts[i].ModTime.Before(scripts[j].ModTime)
+	})
+	return scripts, nil
+}
+
+// enforceScriptQuota makes room for one more script in dir. When the
+// quota is exceeded the oldest scripts are removed right away, unless
+// opts.RefuseOverQuota asks for an error instead. Scripts owned by other
+// users are never removed, so the quota can still be exceeded after
+// cleanup.
+func enforceScriptQuota(dir string, opts *Options) error {
+	if opts.MaxScripts <= 0 && opts.MaxScriptBytes <= 0 {
+		return nil
+	}
+
+	scripts, err := listScripts(dir)
+	if err != nil {
+		return nil // Unreadable directory: creating the script reports the real problem
+	}
+
+	count := len(scripts)
+	var size int64
+	for _, s := range scripts {
+		size += s.Size
+	}
+
+	if !opts.RefuseOverQuota {
+		for _, s := range scripts {
+			if withinScriptQuota(count, size, opts) {
+				break
+			}
+			if s.Removable && os.Remove(s.Path) == nil {
+				count--
+				size -= s.Size
+			}
+		}
+		if opts.DebugMode && len(scripts) != count {
+			fmt.Fprintf(os.Stderr, "autocd: quota cleanup removed %d scripts from %s\n", len(scripts)-count, dir)
+		}
+	}
+
+	if !withinScriptQuota(count, size, opts) {
+		return fmt.Errorf("%w: %d scripts using %d bytes in %s (limits: %d scripts, %d bytes)",
+			ErrScriptQuotaExceeded, count, size, dir, opts.MaxScripts, opts.MaxScriptBytes)
+	}
+	return nil
+}
+
+// withinScriptQuota reports whether another script fits under the limits
+func withinScriptQuota(count int, size int64, opts *Options) bool {
+	if opts.MaxScripts > 0 && count >= opts.MaxScripts {
+		return false
+	}
+	if opts.MaxScriptBytes > 0 && size >= opts.MaxScriptBytes {
+		return false
+	}
+	return true
+}
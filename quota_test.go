@@ -0,0 +1,71 @@
+package autocd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeAgedScripts creates n autocd scripts with increasing modification times
+func writeAgedScripts(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	var paths []string
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("autocd_test%d.sh", i))
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0700); err != nil {
+			t.Fatalf("Failed to write script: %v", err)
+		}
+		stamp := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, stamp, stamp)
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// Test that exceeding the quota removes the oldest scripts
+func TestEnforceScriptQuota_Cleanup(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeAgedScripts(t, dir, 5)
+	os.WriteFile(filepath.Join(dir, "unrelated.txt"), []byte("keep"), 0600)
+
+	if err := enforceScriptQuota(dir, &Options{MaxScripts: 3}); err != nil {
+		t.Fatalf("Quota cleanup should succeed: %v", err)
+	}
+
+	for i, path := range paths {
+		_, err := os.Stat(path)
+		if removed := os.IsNotExist(err); removed != (i < 3) {
+			t.Errorf("Script %d: removed=%v, expected removed=%v", i, removed, i < 3)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unrelated.txt")); err != nil {
+		t.Error("Non-autocd files must not be touched")
+	}
+}
+
+// Test the byte quota and the refuse mode
+func TestEnforceScriptQuota_Refuse(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeAgedScripts(t, dir, 2)
+
+	err := enforceScriptQuota(dir, &Options{MaxScriptBytes: 10, RefuseOverQuota: true})
+	if !errors.Is(err, ErrScriptQuotaExceeded) {
+		t.Fatalf("Expected ErrScriptQuotaExceeded, got: %v", err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Error("Refuse mode must not remove scripts")
+		}
+	}
+
+	if err := enforceScriptQuota(dir, &Options{MaxScriptBytes: 100, MaxScripts: 10}); err != nil {
+		t.Errorf("Usage within quota should pass: %v", err)
+	}
+	if err := enforceScriptQuota(dir, &Options{}); err != nil {
+		t.Errorf("No quota configured should pass: %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// shellLaunch describes how the inherited shell is started beyond its path:
+// extra arguments, extra environment and the temporary files backing them
+type shellLaunch struct {
+	Args      []string     // Arguments appended to the shell invocation
+	Env       []envVar     // Variables exported before exec'ing the shell
+	Artifacts []string     // Temporary files/directories created for this launch
+	RunAs     *sudoInvoker // Start the shell as this user through sudo (nil = current user)
+	LoginRC   bool         // The injected rc replays login startup, so -l must not be passed
+}
+
+// rc dialects understood by the injection mechanism
+const (
+	rcDialectPOSIX = "posix"
+	rcDialectZsh   = "zsh"
+	rcDialectFish  = "fish"
+	rcDialectYSH   = "ysh"
+)
+
+// needsRCInjection reports whether any option requires customizing the
+// spawned shell through a generated rc file
+func needsRCInjection(opts *Options) bool {
+	return opts.PromptPrefix != "" || opts.BackFunction != "" || opts.RelaunchFunction != "" ||
+		len(opts.ShellFunctions) > 0 || len(opts.ShellAliases) > 0
+}
+
+// validFunctionName matches names that are safe to define in every dialect
+var validFunctionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// prepareShellLaunch writes the temporary rc artifacts required by the
+// options and returns how the shell must be started to load them. The
+// generated rc always sources the user's real configuration first.
+func prepareShellLaunch(shell *ShellInfo, opts *Options) (*shellLaunch, error) {
+	launch := &shellLaunch{}
+	if !needsRCInjection(opts) {
+		return launch, nil
+	}
+
+	if opts.BackFunction != "" && !validFunctionName.MatchString(opts.BackFunction) {
+		return nil, fmt.Errorf("invalid back function name %q", opts.BackFunction)
+	}
+	if opts.RelaunchFunction != "" && !validFunctionName.MatchString(opts.RelaunchFunction) {
+		return nil, fmt.Errorf("invalid relaunch function name %q", opts.RelaunchFunction)
+	}
+	for name := range opts.ShellFunctions {
+		if !validFunctionName.MatchString(name) {
+			return nil, fmt.Errorf("invalid shell function name %q", name)
+		}
+	}
+	for name := range opts.ShellAliases {
+		if !validFunctionName.MatchString(name) {
+			return nil, fmt.Errorf("invalid shell alias name %q", name)
+		}
+	}
+
+	tempDir := opts.TempDir
+	if tempDir == "" {
+		tempDir = systemTempDir()
+	}
+
+	var err error
+	switch shell.Type() {
+	case ShellBash:
+		err = launch.injectBash(tempDir, opts)
+	case ShellZsh:
+		err = launch.injectZsh(tempDir, opts)
+	case ShellFish:
+		err = launch.injectFish(tempDir, opts)
+	case ShellOSH:
+		err = launch.injectOSH(tempDir, opts)
+	case ShellYSH:
+		err = launch.injectYSH(tempDir, opts)
+	case ShellSh, ShellDash, ShellKsh:
+		err = launch.injectPOSIXEnv(tempDir, opts)
+	default:
+		if opts.DebugMode {
+			fmt.Fprintf(os.Stderr, "autocd: rc injection not supported for %s, skipping\n", shellName(shell.Path))
+		}
+	}
+
+	if err != nil {
+		launch.remove()
+		return nil, err
+	}
+	return launch, nil
+}
+
+// injectBash starts bash with --rcfile pointing at a generated rc. Login
+// shells ignore --rcfile, so with Options.LoginShell the rc loads the
+// profile files instead and bash is started without -l.
+func (l *shellLaunch) injectBash(tempDir string, opts *Options) error {
+	content := "# autocd rc - load the user's configuration first\n" +
+		"[ -f /etc/bash.bashrc ] && . /etc/bash.bashrc\n" +
+		"[ -n \"$HOME\" ] && [ -f \"$HOME/.bashrc\" ] && . \"$HOME/.bashrc\"\n\n" +
+		rcCustomizations(rcDialectPOSIX, opts)
+	if opts.LoginShell {
+		content = "# autocd rc - load the user's login configuration first\n" +
+			"[ -f /etc/profile ] && . /etc/profile\n" +
+			"if [ -n \"$HOME\" ] && [ -f \"$HOME/.bash_profile\" ]; then . \"$HOME/.bash_profile\"\n" +
+			"elif [ -n \"$HOME\" ] && [ -f \"$HOME/.bash_login\" ]; then . \"$HOME/.bash_login\"\n" +
+			"elif [ -n \"$HOME\" ] && [ -f \"$HOME/.profile\" ]; then . \"$HOME/.profile\"; fi\n\n" +
+			rcCustomizations(rcDialectPOSIX, opts)
+		l.LoginRC = true
+	}
+
+	rcPath, err := l.writeFile(content, rcDialectPOSIX, tempDir, appName(opts))
+	if err != nil {
+		return err
+	}
+	l.Args = append(l.Args, "--rcfile", rcPath)
+	return nil
+}
+
+// injectPOSIXEnv uses $ENV, which POSIX shells read when interactive
+func (l *shellLaunch) injectPOSIXEnv(tempDir string, opts *Options) error {
+	content := "# autocd rc - load the user's $ENV file first\n" +
+		"ENV=\"$AUTOCD_ORIG_ENV\"\n" +
+		"[ -n \"$ENV\" ] && [ -f \"$ENV\" ] && . \"$ENV\"\n\n" +
+		rcCustomizations(rcDialectPOSIX, opts)
+
+	rcPath, err := l.writeFile(content, rcDialectPOSIX, tempDir, appName(opts))
+	if err != nil {
+		return err
+	}
+	l.Env = append(l.Env,
+		envVar{Name: "AUTOCD_ORIG_ENV", Value: os.Getenv("ENV")},
+		envVar{Name: "ENV", Value: rcPath},
+	)
+	return nil
+}
+
+// injectZsh points ZDOTDIR at a generated directory whose startup files
+// chain to the user's real ones and restore ZDOTDIR before .zshrc ends
+func (l *shellLaunch) injectZsh(tempDir string, opts *Options) error {
+	dir, err := os.MkdirTemp(tempDir, artifactPattern(appName(opts), ".rc"))
+	if err != nil {
+		return fmt.Errorf("failed to create zsh rc directory: %w", err)
+	}
+	l.Artifacts = append(l.Artifacts, dir)
+
+	files := map[string]string{
+		".zshenv": "# autocd rc - load the user's .zshenv, keep startup files redirected\n" +
+			"ZDOTDIR=\"${AUTOCD_ORIG_ZDOTDIR:-$HOME}\"\n" +
+			"[ -n \"$ZDOTDIR\" ] && [ -f \"$ZDOTDIR/.zshenv\" ] && . \"$ZDOTDIR/.zshenv\"\n" +
+			"AUTOCD_USER_ZDOTDIR=\"$ZDOTDIR\"\n" +
+			"ZDOTDIR=" + shellQuote(dir) + "\n",
+		".zprofile": "# autocd rc - load the user's .zprofile\n" +
+			"[ -n \"$AUTOCD_USER_ZDOTDIR\" ] && [ -f \"$AUTOCD_USER_ZDOTDIR/.zprofile\" ] && . \"$AUTOCD_USER_ZDOTDIR/.zprofile\"\n",
+		".zshrc": "# autocd rc - restore ZDOTDIR and load the user's .zshrc first\n" +
+			rcSelfRemoval(rcDialectZsh, dir) +
+			"ZDOTDIR=\"$AUTOCD_USER_ZDOTDIR\"\n" +
+			"unset AUTOCD_USER_ZDOTDIR\n" +
+			"[ -n \"$ZDOTDIR\" ] && [ -f \"$ZDOTDIR/.zshrc\" ] && . \"$ZDOTDIR/.zshrc\"\n\n" +
+			rcCustomizations(rcDialectZsh, opts),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to write zsh rc file: %w", err)
+		}
+	}
+
+	l.Env = append(l.Env,
+		envVar{Name: "AUTOCD_ORIG_ZDOTDIR", Value: os.Getenv("ZDOTDIR")},
+		envVar{Name: "ZDOTDIR", Value: dir},
+	)
+	return nil
+}
+
+// injectFish sources a generated file via --init-command, which fish runs
+// after its own configuration has been loaded
+func (l *shellLaunch) injectFish(tempDir string, opts *Options) error {
+	content := "# autocd rc - fish has already loaded the user's configuration\n" +
+		rcCustomizations(rcDialectFish, opts)
+
+	rcPath, err := l.writeFile(content, rcDialectFish, tempDir, appName(opts))
+	if err != nil {
+		return err
+	}
+	l.Args = append(l.Args, "--init-command", "source "+fishQuote(rcPath))
+	return nil
+}
+
+// injectOSH starts osh, which mirrors bash's --rcfile, with a generated rc
+// that loads the user's oshrc
+func (l *shellLaunch) injectOSH(tempDir string, opts *Options) error {
+	content := "# autocd rc - load the user's oshrc first\n" +
+		"if [ -f \"$HOME/.config/oils/oshrc\" ]; then . \"$HOME/.config/oils/oshrc\"\n" +
+		"elif [ -f \"$HOME/.config/oil/oshrc\" ]; then . \"$HOME/.config/oil/oshrc\"; fi\n\n" +
+		rcCustomizations(rcDialectPOSIX, opts)
+
+	rcPath, err := l.writeFile(content, rcDialectPOSIX, tempDir, appName(opts))
+	if err != nil {
+		return err
+	}
+	l.Args = append(l.Args, "--rcfile", rcPath)
+	return nil
+}
+
+// injectYSH starts ysh with --rcfile. YSH rejects POSIX function syntax,
+// and environment access differs between releases, so the rc uses literal
+// paths and only supports the back and relaunch functions.
+func (l *shellLaunch) injectYSH(tempDir string, opts *Options) error {
+	var b strings.Builder
+	b.WriteString("# autocd rc - load the user's yshrc first\n")
+	if home := os.Getenv("HOME"); home != "" {
+		yshrc := yshQuote(filepath.Join(home, ".config", "oils", "yshrc"))
+		b.WriteString("if test -f " + yshrc + " { source " + yshrc + " }\n")
+	}
+	if opts.BackFunction != "" {
+		body := "exit 0"
+		if !opts.BackExits {
+			source, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get source directory: %w", err)
+			}
+			body = "cd " + yshQuote(source)
+		}
+		b.WriteString("proc " + opts.BackFunction + " { " + body + " }\n")
+	}
+	if opts.RelaunchFunction != "" {
+		b.WriteString(renderRelaunchFunction(rcDialectYSH, opts.RelaunchFunction, relaunchCommand(os.Args)))
+	}
+	if opts.DebugMode && (opts.PromptPrefix != "" || len(opts.ShellFunctions) > 0 || len(opts.ShellAliases) > 0) {
+		fmt.Fprintf(os.Stderr, "autocd: prompt prefix, shell functions and aliases are not supported for ysh, skipping\n")
+	}
+
+	rcPath, err := l.writeFile(b.String(), rcDialectYSH, tempDir, appName(opts))
+	if err != nil {
+		return err
+	}
+	l.Args = append(l.Args, "--rcfile", rcPath)
+	return nil
+}
+
+// writeFile stores an rc file next to the transition scripts. The file
+// deletes itself as soon as the shell starts reading it.
+func (l *shellLaunch) writeFile(content, dialect, tempDir, app string) (string, error) {
+	path, err := createTemporaryScript("", ".rc", tempDir, app)
+	if err != nil {
+		return "", err
+	}
+	l.Artifacts = append(l.Artifacts, path)
+
+	header, body, _ := strings.Cut(content, "\n")
+	content = header + "\n" + rcSelfRemoval(dialect, path) + body
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write rc file: %w", err)
+	}
+	return path, nil
+}
+
+// rcSelfRemoval renders the line deleting a loaded rc artifact. The shell
+// keeps reading through its open descriptor, and removing the artifact
+// first means an exit or exec in the user's configuration can't leave it
+// behind.
+func rcSelfRemoval(dialect, path string) string {
+	switch dialect {
+	case rcDialectFish:
+		return "command rm -rf -- " + fishQuote(path) + " 2>/dev/null\n"
+	case rcDialectYSH:
+		return "command rm -rf -- " + yshQuote(path) + " 2>/dev/null\n"
+	}
+	return "command rm -rf -- " + shellQuote(path) + " 2>/dev/null\n"
+}
+
+// remove deletes the launch artifacts (used when the transition fails)
+func (l *shellLaunch) remove() {
+	for _, path := range l.Artifacts {
+		os.RemoveAll(path)
+	}
+}
+
+// rcCustomizations renders the autocd customizations applied after the
+// user's configuration has been loaded
+func rcCustomizations(dialect string, opts *Options) string {
+	var b strings.Builder
+
+	if opts.PromptPrefix != "" {
+		switch dialect {
+		case rcDialectFish:
+			b.WriteString("if functions -q fish_prompt\n" +
+				"    functions -c fish_prompt __autocd_fish_prompt\n" +
+				"    function fish_prompt\n" +
+				"        printf '%s' \"$AUTOCD_PROMPT_PREFIX\"\n" +
+				"        __autocd_fish_prompt\n" +
+				"    end\n" +
+				"end\n")
+		case rcDialectZsh:
+			b.WriteString("[[ \"$PS1\" == \"$AUTOCD_PROMPT_PREFIX\"* ]] || PS1=\"$AUTOCD_PROMPT_PREFIX$PS1\"\n")
+		default:
+			b.WriteString("case \"$PS1\" in\n" +
+				"    \"$AUTOCD_PROMPT_PREFIX\"*) ;;\n" +
+				"    *) PS1=\"$AUTOCD_PROMPT_PREFIX$PS1\" ;;\n" +
+				"esac\n")
+		}
+	}
+
+	if opts.BackFunction != "" {
+		b.WriteString(renderBackFunction(dialect, opts.BackFunction, opts.BackExits))
+	}
+	if opts.RelaunchFunction != "" {
+		b.WriteString(renderRelaunchFunction(dialect, opts.RelaunchFunction, relaunchCommand(os.Args)))
+	}
+
+	for _, name := range sortedNames(opts.ShellFunctions) {
+		if body := opts.ShellFunctions[name].forDialect(dialect); body != "" {
+			b.WriteString(renderFunction(dialect, name, body))
+		}
+	}
+	for _, name := range sortedNames(opts.ShellAliases) {
+		if value := opts.ShellAliases[name].forDialect(dialect); value != "" {
+			b.WriteString(renderAlias(dialect, name, value))
+		}
+	}
+
+	return b.String()
+}
+
+// forDialect picks the definition for an rc dialect; zsh falls back to POSIX
+func (d ShellDefinition) forDialect(dialect string) string {
+	switch dialect {
+	case rcDialectFish:
+		return d.Fish
+	case rcDialectZsh:
+		if d.Zsh != "" {
+			return d.Zsh
+		}
+	}
+	return d.POSIX
+}
+
+// sortedNames returns map keys in a stable order for reproducible rc files
+func sortedNames(defs map[string]ShellDefinition) []string {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderFunction defines an application-provided function; the body is
+// shell code in the dialect's syntax and is not escaped
+func renderFunction(dialect, name, body string) string {
+	if dialect == rcDialectFish {
+		return "function " + name + "\n" + body + "\nend\n"
+	}
+	return name + "() {\n" + body + "\n}\n"
+}
+
+// renderAlias defines an application-provided alias with a quoted expansion
+func renderAlias(dialect, name, value string) string {
+	if dialect == rcDialectFish {
+		return "alias " + name + " " + fishQuote(value) + "\n"
+	}
+	return "alias " + name + "=" + shellQuote(value) + "\n"
+}
+
+// renderBackFunction defines a function returning to the directory the
+// application was launched from, or leaving the nested shell entirely
+func renderBackFunction(dialect, name string, exits bool) string {
+	body := `cd "$AUTOCD_SOURCE_DIR"`
+	if exits {
+		body = "exit 0"
+	}
+
+	if dialect == rcDialectFish {
+		if !exits {
+			body = "cd $AUTOCD_SOURCE_DIR"
+		}
+		return "function " + name + "\n    " + body + "\nend\n"
+	}
+	return name + "() { " + body + "; }\n"
+}
+
+// relaunchCommand captures the application's command line for the relaunch
+// function. A relative program path is made absolute because the function
+// runs from the new directory; bare names are still looked up in PATH.
+func relaunchCommand(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	command := append([]string(nil), args...)
+	if strings.Contains(command[0], "/") && !filepath.IsAbs(command[0]) {
+		if abs, err := filepath.Abs(command[0]); err == nil {
+			command[0] = abs
+		}
+	}
+	return command
+}
+
+// renderRelaunchFunction defines a function re-running the application with
+// its original arguments, followed by any given to the function
+func renderRelaunchFunction(dialect, name string, command []string) string {
+	if len(command) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		switch dialect {
+		case rcDialectFish:
+			quoted[i] = fishQuote(arg)
+		case rcDialectYSH:
+			quoted[i] = yshQuote(arg)
+		default:
+			quoted[i] = shellQuote(arg)
+		}
+	}
+	line := strings.Join(quoted, " ")
+
+	switch dialect {
+	case rcDialectFish:
+		return "function " + name + "\n    " + line + " $argv\nend\n"
+	case rcDialectYSH:
+		return "proc " + name + " (...args) { " + line + " @args }\n"
+	}
+	return name + "() { " + line + ` "$@"; }` + "\n"
+}
+
+// shellQuote wraps a value in single quotes for POSIX shells
+func shellQuote(value string) string {
+	return "'" + sanitizePathForShell(value) + "'"
+}
+
+// fishQuote wraps a value in single quotes for fish, which uses backslash
+// escapes inside single-quoted strings
+func fishQuote(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+// yshQuote quotes a value for YSH. Single-quoted strings are raw, so
+// values containing quotes, backslashes, control characters or non-ASCII
+// bytes use a b'...' string, with \yHH escapes for the bytes.
+func yshQuote(value string) string {
+	raw := true
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c == '\'' || c == '\\' || c < 0x20 || c >= 0x7f {
+			raw = false
+			break
+		}
+	}
+	if raw {
+		return "'" + value + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("b'")
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\'' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\y%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString("'")
+	return b.String()
+}
+
+// nuQuote quotes a value for nushell: single quotes when the value has
+// none (they take no escapes), otherwise a raw string r#'...'# with enough
+// hashes that the value cannot close it
+func nuQuote(value string) string {
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	hashes := "#"
+	for strings.Contains(value, "'"+hashes) {
+		hashes += "#"
+	}
+	return "r" + hashes + "'" + value + "'" + hashes
+}
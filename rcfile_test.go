@@ -0,0 +1,463 @@
+package autocd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that no rc artifacts are created without customizations
+func TestPrepareShellLaunch_NoCustomizations(t *testing.T) {
+	launch, err := prepareShellLaunch(&ShellInfo{Path: "/bin/bash", IsValid: true}, &Options{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	if len(launch.Args) != 0 || len(launch.Env) != 0 || len(launch.Artifacts) != 0 {
+		t.Errorf("Expected empty launch, got %+v", launch)
+	}
+}
+
+// Test per-shell injection mechanisms
+func TestPrepareShellLaunch_PerShell(t *testing.T) {
+	tests := []struct {
+		shell     string
+		wantArg   string
+		wantEnv   string
+		artifacts int
+	}{
+		{"/bin/bash", "--rcfile", "", 1},
+		{"/usr/bin/zsh", "", "ZDOTDIR", 1},
+		{"/usr/bin/fish", "--init-command", "", 1},
+		{"/bin/dash", "", "ENV", 1},
+		{"/usr/bin/tcsh", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.shell), func(t *testing.T) {
+			tempDir := t.TempDir()
+			opts := &Options{TempDir: tempDir, PromptPrefix: "(test) "}
+
+			launch, err := prepareShellLaunch(&ShellInfo{Path: tt.shell, IsValid: true}, opts)
+			if err != nil {
+				t.Fatalf("prepareShellLaunch failed: %v", err)
+			}
+			defer launch.remove()
+
+			if tt.wantArg != "" && (len(launch.Args) == 0 || launch.Args[0] != tt.wantArg) {
+				t.Errorf("Expected %s argument, got %v", tt.wantArg, launch.Args)
+			}
+			if tt.wantEnv != "" {
+				found := false
+				for _, v := range launch.Env {
+					if v.Name == tt.wantEnv {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("Expected %s in launch environment, got %v", tt.wantEnv, launch.Env)
+				}
+			}
+			if len(launch.Artifacts) != tt.artifacts {
+				t.Errorf("Expected %d artifacts, got %v", tt.artifacts, launch.Artifacts)
+			}
+			for _, artifact := range launch.Artifacts {
+				if !strings.HasPrefix(artifact, tempDir) {
+					t.Errorf("Artifact %s created outside temp dir", artifact)
+				}
+				if pid, ok := scriptOwnerPID(filepath.Base(artifact)); !ok || pid != os.Getpid() {
+					t.Errorf("Artifact %s should follow the script naming scheme", artifact)
+				}
+			}
+		})
+	}
+}
+
+// Test that a loaded rc removes itself but still runs to the end
+func TestRCInjection_SelfRemoval(t *testing.T) {
+	tempDir := t.TempDir()
+	launch, err := prepareShellLaunch(&ShellInfo{Path: "/bin/sh", IsValid: true}, &Options{TempDir: tempDir, PromptPrefix: "(test) "})
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	defer launch.remove()
+	rcPath := launch.Artifacts[0]
+
+	cmd := exec.Command("/bin/sh", "-c", ". \"$0\"; echo \"$PS1\"", rcPath)
+	cmd.Env = append(os.Environ(), "AUTOCD_PROMPT_PREFIX=(test) ")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Sourcing the rc failed: %v (%s)", err, out)
+	}
+	if !strings.HasPrefix(string(out), "(test) ") {
+		t.Errorf("The rc should still apply its customizations, got %q", out)
+	}
+	if _, err := os.Stat(rcPath); !os.IsNotExist(err) {
+		t.Error("The rc file should have removed itself")
+	}
+}
+
+// Test that the cleanup sweep reclaims stale zsh rc directories
+func TestCleanupOldScripts_RCDirectories(t *testing.T) {
+	tempDir := t.TempDir()
+	launch, err := prepareShellLaunch(&ShellInfo{Path: "/usr/bin/zsh", IsValid: true}, &Options{TempDir: tempDir, PromptPrefix: "(test) "})
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	rcDir := launch.Artifacts[0]
+	oldTime := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(rcDir, oldTime, oldTime)
+
+	if err := cleanupOldScriptsInDir(tempDir, time.Hour); err != nil {
+		t.Fatalf("cleanupOldScriptsInDir failed: %v", err)
+	}
+	if _, err := os.Stat(rcDir); err != nil {
+		t.Error("A fresh rc directory of a live process should be kept")
+	}
+
+	// Name-encoded age decides; simulate an old one by renaming
+	stale := filepath.Join(tempDir, "autocd_"+strconv.Itoa(os.Getpid())+"_"+strconv.FormatInt(oldTime.Unix(), 10)+"_1.rc")
+	if err := os.Rename(rcDir, stale); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := cleanupOldScriptsInDir(tempDir, time.Hour); err != nil {
+		t.Fatalf("cleanupOldScriptsInDir failed: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("A stale rc directory should have been removed with its files")
+	}
+}
+
+// Test that the bash rc loads the user's .bashrc before applying the prefix
+func TestBashRCInjection_AppliesPromptAfterUserRC(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, ".bashrc"), []byte("PS1='user> '\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .bashrc: %v", err)
+	}
+
+	opts := &Options{TempDir: t.TempDir(), PromptPrefix: "(app) "}
+	launch, err := prepareShellLaunch(&ShellInfo{Path: bash, IsValid: true}, opts)
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	defer launch.remove()
+
+	args := append(launch.Args, "-i", "-c", `printf '%s' "$PS1"`)
+	cmd := exec.Command(bash, args...)
+	cmd.Env = []string{"HOME=" + home, "PATH=" + os.Getenv("PATH"), "AUTOCD_PROMPT_PREFIX=(app) "}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+
+	if string(output) != "(app) user> " {
+		t.Errorf("Expected prefixed user prompt, got %q", output)
+	}
+}
+
+// Test fish quoting of init-command paths
+func TestFishQuote(t *testing.T) {
+	got := fishQuote(`/tmp/it's\here`)
+	expected := `'/tmp/it\'s\\here'`
+	if got != expected {
+		t.Errorf("fishQuote() = %s, want %s", got, expected)
+	}
+}
+
+// Test the injected back function in bash
+func TestBashRCInjection_BackFunction(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	source := t.TempDir()
+	opts := &Options{TempDir: t.TempDir(), BackFunction: "back"}
+	launch, err := prepareShellLaunch(&ShellInfo{Path: bash, IsValid: true}, opts)
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	defer launch.remove()
+
+	args := append(launch.Args, "-i", "-c", `cd / && back && pwd`)
+	cmd := exec.Command(bash, args...)
+	cmd.Env = []string{"HOME=" + t.TempDir(), "PATH=" + os.Getenv("PATH"), "AUTOCD_SOURCE_DIR=" + source}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	if strings.TrimSpace(string(output)) != source {
+		t.Errorf("back should return to %s, got %q", source, output)
+	}
+}
+
+// Test back function rendering and name validation
+func TestRenderBackFunction(t *testing.T) {
+	if got := renderBackFunction(rcDialectPOSIX, "back", true); got != "back() { exit 0; }\n" {
+		t.Errorf("Unexpected exit variant: %q", got)
+	}
+	if got := renderBackFunction(rcDialectFish, "back", false); !strings.Contains(got, "function back\n    cd $AUTOCD_SOURCE_DIR") {
+		t.Errorf("Unexpected fish variant: %q", got)
+	}
+
+	_, err := prepareShellLaunch(&ShellInfo{Path: "/bin/bash", IsValid: true}, &Options{TempDir: t.TempDir(), BackFunction: "x; rm -rf /"})
+	if err == nil {
+		t.Error("Invalid function names should be rejected")
+	}
+}
+
+// Test application-provided functions and aliases in bash
+func TestBashRCInjection_FunctionsAndAliases(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	opts := &Options{
+		TempDir: t.TempDir(),
+		ShellFunctions: map[string]ShellDefinition{
+			"greet": {POSIX: `echo "hello $1"`, Fish: `echo "hello $argv[1]"`},
+		},
+		ShellAliases: map[string]ShellDefinition{
+			"say": {POSIX: `echo "it's said"`},
+		},
+	}
+	launch, err := prepareShellLaunch(&ShellInfo{Path: bash, IsValid: true}, opts)
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	defer launch.remove()
+
+	args := append(launch.Args, "-i", "-c", "greet world\nsay")
+	cmd := exec.Command(bash, args...)
+	cmd.Env = []string{"HOME=" + t.TempDir(), "PATH=" + os.Getenv("PATH")}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	if got := string(output); got != "hello world\nit's said\n" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+}
+
+// Test that a bash login shell still gets the rc customizations, with the
+// profile files loaded by the injected rc
+func TestBashRCInjection_LoginShell(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, ".bash_profile"), []byte("FROM_PROFILE=yes\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .bash_profile: %v", err)
+	}
+	opts := &Options{
+		TempDir:        t.TempDir(),
+		LoginShell:     true,
+		ShellFunctions: map[string]ShellDefinition{"greet": {POSIX: `echo "hello $1"`}},
+	}
+	shell := &ShellInfo{Path: bash, IsValid: true, Args: []string{"-i", "-c", `echo "$FROM_PROFILE"; greet world`}}
+	launch, err := prepareShellLaunch(shell, opts)
+	if err != nil {
+		t.Fatalf("prepareShellLaunch failed: %v", err)
+	}
+	defer launch.remove()
+
+	args := launchArgs(shell, opts, launch)
+	for _, arg := range args {
+		if arg == "-l" {
+			t.Fatalf("bash would ignore --rcfile as a login shell: %v", args)
+		}
+	}
+	cmd := exec.Command(bash, args...)
+	cmd.Env = []string{"HOME=" + home, "PATH=" + os.Getenv("PATH")}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	if got := string(output); got != "yes\nhello world\n" {
+		t.Errorf("Expected the profile and the function, got %q", got)
+	}
+}
+
+// Test dialect selection and rendering of functions and aliases
+func TestRCCustomizations_FunctionsAndAliases(t *testing.T) {
+	opts := &Options{
+		ShellFunctions: map[string]ShellDefinition{
+			"b": {POSIX: "echo posix", Zsh: "echo zsh"},
+			"a": {POSIX: "echo only-posix"},
+		},
+		ShellAliases: map[string]ShellDefinition{
+			"ll": {POSIX: "ls -l", Fish: "ls -l"},
+		},
+	}
+
+	posix := rcCustomizations(rcDialectPOSIX, opts)
+	expected := "a() {\necho only-posix\n}\nb() {\necho posix\n}\nalias ll='ls -l'\n"
+	if posix != expected {
+		t.Errorf("Unexpected POSIX rc:\n%s\nwant:\n%s", posix, expected)
+	}
+
+	if zsh := rcCustomizations(rcDialectZsh, opts); !strings.Contains(zsh, "b() {\necho zsh\n}") ||
+		!strings.Contains(zsh, "a() {\necho only-posix\n}") {
+		t.Errorf("zsh should use its own body and fall back to POSIX:\n%s", zsh)
+	}
+
+	fish := rcCustomizations(rcDialectFish, opts)
+	if fish != "alias ll 'ls -l'\n" {
+		t.Errorf("fish should only get definitions with a fish body: %q", fish)
+	}
+
+	_, err := prepareShellLaunch(&ShellInfo{Path: "/bin/bash", IsValid: true}, &Options{
+		TempDir:      t.TempDir(),
+		ShellAliases: map[string]ShellDefinition{"x;y": {POSIX: "true"}},
+	})
+	if err == nil {
+		t.Error("Invalid alias names should be rejected")
+	}
+}
+
+// Test the relaunch function re-runs the captured command line with extra arguments
+func TestRelaunchFunction(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	command := []string{"printf", "%s|", "it's"}
+	script := renderRelaunchFunction(rcDialectPOSIX, "again", command) + "again extra"
+	output, err := exec.Command(sh, "-c", script).Output()
+	if err != nil {
+		t.Fatalf("sh failed: %v", err)
+	}
+	if string(output) != "it's|extra|" {
+		t.Errorf("Unexpected relaunch output: %q", output)
+	}
+
+	if got := renderRelaunchFunction(rcDialectFish, "again", []string{"app", "-x"}); got != "function again\n    'app' '-x' $argv\nend\n" {
+		t.Errorf("Unexpected fish variant: %q", got)
+	}
+	if got := relaunchCommand([]string{"./bin/app", "-x"}); !filepath.IsAbs(got[0]) || got[1] != "-x" {
+		t.Errorf("Relative program path should be made absolute: %v", got)
+	}
+	if got := relaunchCommand([]string{"app"}); got[0] != "app" {
+		t.Errorf("Bare program names should be left for PATH lookup: %v", got)
+	}
+
+	_, err = prepareShellLaunch(&ShellInfo{Path: "/bin/bash", IsValid: true}, &Options{TempDir: t.TempDir(), RelaunchFunction: "again()"})
+	if err == nil {
+		t.Error("Invalid relaunch function names should be rejected")
+	}
+}
+
+// Test YSH quoting: raw single quotes when possible, byte strings otherwise
+func TestYSHQuote(t *testing.T) {
+	tests := map[string]string{
+		"/home/user/my dir": `'/home/user/my dir'`,
+		"it's":              `b'it\'s'`,
+		`C:\dir`:            `b'C:\\dir'`,
+		"line\nbreak":       `b'line\y0abreak'`,
+		"café":              `b'caf\yc3\ya9'`,
+		"$HOME":             `'$HOME'`,
+	}
+	for value, expected := range tests {
+		if got := yshQuote(value); got != expected {
+			t.Errorf("yshQuote(%q) = %s, want %s", value, got, expected)
+		}
+	}
+}
+
+// Test rc injection for the Oils shells
+func TestOilsRCInjection(t *testing.T) {
+	opts := &Options{TempDir: t.TempDir(), BackFunction: "back", RelaunchFunction: "again"}
+
+	for _, name := range []string{"osh", "ysh"} {
+		launch, err := prepareShellLaunch(&ShellInfo{Path: "/usr/bin/" + name, IsValid: true}, opts)
+		if err != nil {
+			t.Fatalf("prepareShellLaunch(%s) failed: %v", name, err)
+		}
+		if len(launch.Args) != 2 || launch.Args[0] != "--rcfile" {
+			t.Fatalf("Expected %s to load the rc through --rcfile, got %v", name, launch.Args)
+		}
+		content, err := os.ReadFile(launch.Args[1])
+		launch.remove()
+		if err != nil {
+			t.Fatalf("Failed to read %s rc: %v", name, err)
+		}
+
+		rc := string(content)
+		switch name {
+		case "osh":
+			if !strings.Contains(rc, "/.config/oils/oshrc") || !strings.Contains(rc, "back() {") {
+				t.Errorf("Unexpected osh rc:\n%s", rc)
+			}
+		case "ysh":
+			if !strings.Contains(rc, "proc back { cd ") || !strings.Contains(rc, "proc again (...args) {") || strings.Contains(rc, "() {") {
+				t.Errorf("Unexpected ysh rc:\n%s", rc)
+			}
+		}
+	}
+}
+
+// Test that fish can be asked to perform the cd itself
+func TestGenerateScript_NativeCD(t *testing.T) {
+	bin := t.TempDir()
+	fish := filepath.Join(bin, "fish")
+	if err := os.WriteFile(fish, []byte("#!/bin/sh\nprintf '%s\\n' \"$@\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake fish: %v", err)
+	}
+	target := filepath.Join(t.TempDir(), "it's here")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+
+	shell := &ShellInfo{Path: fish, IsValid: true}
+	script, err := generateScript(target, shell, &Options{NativeCD: true, PlainOutput: true}, nil)
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+	output, err := exec.Command("/bin/sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if !strings.HasSuffix(string(output), "--init-command\ncd "+fishQuote(target)+"\n") {
+		t.Errorf("Expected fish to receive the cd as an init command, got:\n%s", output)
+	}
+
+	plain, _ := generateScript(target, shell, &Options{}, nil)
+	if strings.Contains(plain, "--init-command") {
+		t.Error("The native cd should only be used when requested")
+	}
+}
+
+// Test nushell quoting and the per-shell native cd arguments
+func TestNativeCDArgs(t *testing.T) {
+	quotes := map[string]string{
+		"/home/user/my dir": `'/home/user/my dir'`,
+		`C:\dir "x"`:        `'C:\dir "x"'`,
+		"it's":              `r#'it's'#`,
+		"a'#b":              `r##'a'#b'##`,
+	}
+	for value, expected := range quotes {
+		if got := nuQuote(value); got != expected {
+			t.Errorf("nuQuote(%q) = %s, want %s", value, got, expected)
+		}
+	}
+
+	nu := nativeCDArgs(&ShellInfo{Path: "/usr/bin/nu"}, "/srv/it's")
+	if len(nu) != 2 || nu[0] != "--execute" || nu[1] != `cd r#'/srv/it's'#` {
+		t.Errorf("Unexpected nushell arguments: %q", nu)
+	}
+	if args := nativeCDArgs(&ShellInfo{Path: "/usr/bin/elvish"}, "/srv"); args != nil {
+		t.Errorf("elvish has no startup command flag, got %q", args)
+	}
+}
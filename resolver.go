@@ -0,0 +1,82 @@
+package autocd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Resolver turns the spec of a symbolic target ("work" in
+// "bookmark:work") into a directory path
+type Resolver interface {
+	Resolve(spec string) (string, error)
+}
+
+// ResolverFunc adapts a plain function to the Resolver interface
+type ResolverFunc func(spec string) (string, error)
+
+// Resolve calls f(spec).
+func (f ResolverFunc) Resolve(spec string) (string, error) {
+	return f(spec)
+}
+
+// schemeRegex matches the scheme names resolvers can be registered under
+var schemeRegex = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Resolver{}
+)
+
+// RegisterResolver makes targets of the form "<scheme>:<spec>" resolve
+// through r wherever autocd accepts a target (ExitWithDirectory,
+// ValidateDirectory, ResolveDirectory). The resolved path is validated
+// like any other target. Registering a scheme again replaces its resolver.
+func RegisterResolver(scheme string, r Resolver) error {
+	if !schemeRegex.MatchString(scheme) {
+		return fmt.Errorf("invalid resolver scheme %q", scheme)
+	}
+	if r == nil {
+		return errors.New("resolver is nil")
+	}
+
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = r
+	return nil
+}
+
+// UnregisterResolver removes the resolver for scheme, if any
+func UnregisterResolver(scheme string) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	delete(resolvers, scheme)
+}
+
+// resolveTarget expands a symbolic target through its registered resolver.
+// Targets without a registered scheme, including ordinary paths that
+// happen to contain a colon, are returned unchanged.
+func resolveTarget(target string) (string, error) {
+	scheme, spec, ok := strings.Cut(target, ":")
+	if !ok || !schemeRegex.MatchString(scheme) {
+		return target, nil
+	}
+
+	resolversMu.RLock()
+	r := resolvers[scheme]
+	resolversMu.RUnlock()
+	if r == nil {
+		return target, nil
+	}
+
+	path, err := r.Resolve(spec)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrTargetUnresolved, target, err)
+	}
+	if path == "" {
+		return "", fmt.Errorf("%w: %s resolved to an empty path", ErrTargetUnresolved, target)
+	}
+	return path, nil
+}
@@ -0,0 +1,54 @@
+package autocd
+
+import (
+	"errors"
+	"testing"
+)
+
+// Test resolving registered schemes and passing other targets through
+func TestResolveTarget(t *testing.T) {
+	dir := t.TempDir()
+	if err := RegisterResolver("bookmark", ResolverFunc(func(spec string) (string, error) {
+		if spec == "work" {
+			return dir, nil
+		}
+		return "", errors.New("no such bookmark")
+	})); err != nil {
+		t.Fatalf("RegisterResolver failed: %v", err)
+	}
+	defer UnregisterResolver("bookmark")
+
+	if got, err := resolveTarget("bookmark:work"); err != nil || got != dir {
+		t.Errorf("Expected %s, got %q (%v)", dir, got, err)
+	}
+	for _, target := range []string{"/tmp/a:b", "recent:3", "relative/dir", "Bookmark:work"} {
+		if got, err := resolveTarget(target); err != nil || got != target {
+			t.Errorf("resolveTarget(%q) should pass through, got %q (%v)", target, got, err)
+		}
+	}
+
+	if _, err := resolveTarget("bookmark:home"); !errors.Is(err, ErrTargetUnresolved) {
+		t.Errorf("Expected ErrTargetUnresolved, got: %v", err)
+	}
+	if err := ValidateDirectory("bookmark:work", SecurityNormal); err != nil {
+		t.Errorf("Resolved target should validate: %v", err)
+	}
+	err := ValidateDirectory("bookmark:home", SecurityNormal)
+	if !errors.Is(err, ErrTargetUnresolved) || !IsPathError(err) {
+		t.Errorf("Expected a path error wrapping ErrTargetUnresolved, got: %v", err)
+	}
+}
+
+// Test that invalid registrations are rejected
+func TestRegisterResolver_Invalid(t *testing.T) {
+	noop := ResolverFunc(func(string) (string, error) { return "/", nil })
+	for _, scheme := range []string{"", "Bookmark", "1st", "a b", "a:b"} {
+		if err := RegisterResolver(scheme, noop); err == nil {
+			t.Errorf("Scheme %q should be rejected", scheme)
+			UnregisterResolver(scheme)
+		}
+	}
+	if err := RegisterResolver("nil", nil); err == nil {
+		t.Error("A nil resolver should be rejected")
+	}
+}
@@ -0,0 +1,60 @@
+package autocd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// TransitionResult is the machine-readable record of a transition, written
+// just before exec for wrappers and supervisors (see Options.ResultFD)
+type TransitionResult struct {
+	TargetDir  string        `json:"target_dir"`
+	ShellPath  string        `json:"shell_path"`
+	ScriptPath string        `json:"script_path"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Phases     []PhaseTiming `json:"phases,omitempty"` // Durations of the phases before exec
+}
+
+// Transition describes a fully prepared transition, as seen by
+// Options.OnBeforeExec
+type Transition struct {
+	TargetDir  string     // Validated directory the shell will start in
+	Shell      *ShellInfo // Shell that will be exec'd
+	ScriptPath string     // Transition script ("" when the shell is exec'd directly)
+}
+
+// fdWriter writes to a raw file descriptor without taking ownership of it
+type fdWriter int
+
+func (fd fdWriter) Write(p []byte) (int, error) {
+	return writeFD(int(fd), p)
+}
+
+// writeTransitionResult emits the JSON result line to the configured
+// writer and/or descriptor. Closed descriptors are skipped silently.
+func writeTransitionResult(result TransitionResult, opts *Options) error {
+	var writers []io.Writer
+	if opts.ResultWriter != nil {
+		writers = append(writers, opts.ResultWriter)
+	}
+	if opts.ResultFD > 0 && fdOpen(opts.ResultFD) {
+		writers = append(writers, fdWriter(opts.ResultFD))
+	}
+	if len(writers) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	for _, w := range writers {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
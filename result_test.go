@@ -0,0 +1,60 @@
+package autocd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test the JSON transition result on a writer and a descriptor
+func TestWriteTransitionResult(t *testing.T) {
+	result := TransitionResult{
+		TargetDir:  "/tmp/project",
+		ShellPath:  "/bin/bash",
+		ScriptPath: "/tmp/autocd_1_2_3.sh",
+		Timestamp:  time.Unix(1700000000, 0).UTC(),
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if err := writeTransitionResult(result, &Options{ResultWriter: &buf, ResultFD: int(w.Fd())}); err != nil {
+		t.Fatalf("writeTransitionResult failed: %v", err)
+	}
+	w.Close()
+
+	var decoded TransitionResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	if !reflect.DeepEqual(decoded, result) {
+		t.Errorf("Decoded %+v, want %+v", decoded, result)
+	}
+
+	fromFD := make([]byte, 512)
+	n, _ := r.Read(fromFD)
+	if !bytes.Equal(fromFD[:n], buf.Bytes()) {
+		t.Errorf("Descriptor received %q, want %q", fromFD[:n], buf.Bytes())
+	}
+}
+
+// Test that closed descriptors are skipped
+func TestWriteTransitionResult_ClosedFD(t *testing.T) {
+	_, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	fd := int(w.Fd())
+	w.Close()
+
+	if err := writeTransitionResult(TransitionResult{}, &Options{ResultFD: fd}); err != nil {
+		t.Errorf("Closed descriptor should be skipped, got: %v", err)
+	}
+}
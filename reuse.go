@@ -0,0 +1,96 @@
+package autocd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// unsafeNameChars matches characters not allowed in file name components
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// reusableScriptPath returns the stable script location for this app and
+// user. The name carries no PID stamp, so orphan cleanup leaves it alone.
+func reusableScriptPath(tempDir, app string) string {
+	app = unsafeNameChars.ReplaceAllString(app, "_")
+	return filepath.Join(tempDir, fmt.Sprintf("autocd_app-%s-%d.sh", app, os.Geteuid()))
+}
+
+// writeReusableScript atomically replaces the app's stable script and
+// returns a /dev/fd path pinned to the content just written. Writers are
+// serialized with a lock file; because the shell opens the inherited
+// descriptor rather than the path, a concurrent instance replacing the
+// script afterwards cannot redirect this transition.
+func writeReusableScript(content, tempDir, app string) (string, func(), error) {
+	if tempDir == "" {
+		tempDir = systemTempDir()
+	}
+	stablePath := reusableScriptPath(tempDir, app)
+
+	lock, err := lockReusableScript(scriptLockPath(stablePath))
+	if err != nil {
+		return "", nil, err
+	}
+	defer lock.Close()
+	defer unlockFile(lock)
+
+	tmpPath, err := createTemporaryScript(content, ".sh", tempDir, app)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.Rename(tmpPath, stablePath); err != nil {
+		os.Remove(tmpPath)
+		return "", nil, fmt.Errorf("failed to replace script: %w", err)
+	}
+
+	script, err := os.Open(stablePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open script: %w", err)
+	}
+	return scriptFDPath(script)
+}
+
+// scriptLockPath returns the lock serializing writers of a stable script.
+// Its name is outside the autocd_ prefix; cleanup handles it separately.
+func scriptLockPath(stablePath string) string {
+	return filepath.Join(filepath.Dir(stablePath), "autocd."+filepath.Base(stablePath)+".lock")
+}
+
+// lockReusableScript opens and exclusively locks the lock file. Cleanup may
+// unlink an old lock file, so the lock only counts when the path still
+// names the locked file; otherwise it is taken again.
+func lockReusableScript(lockPath string) (*os.File, error) {
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open script lock: %w", err)
+		}
+		if err := lockFile(lock); err != nil {
+			lock.Close()
+			return nil, fmt.Errorf("failed to lock script: %w", err)
+		}
+		locked, err := lock.Stat()
+		current, statErr := os.Stat(lockPath)
+		if err == nil && statErr == nil && os.SameFile(locked, current) {
+			return lock, nil
+		}
+		lock.Close()
+	}
+}
+
+// removeScriptLock deletes an old lock file unless a writer holds it
+func removeScriptLock(path string, info fs.FileInfo) {
+	if !removableScript(info) {
+		return
+	}
+	lock, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	defer lock.Close()
+	if tryLockFile(lock) {
+		os.Remove(path)
+	}
+}
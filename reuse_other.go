@@ -0,0 +1,22 @@
+//go:build !unix || aix || solaris
+
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// lockFile is unsupported without flock(2)
+func lockFile(f *os.File) error {
+	return fmt.Errorf("file locking is not supported on %s", runtime.GOOS)
+}
+
+// tryLockFile never succeeds without flock(2), so cleanup keeps lock files
+func tryLockFile(f *os.File) bool {
+	return false
+}
+
+// unlockFile has nothing to release without flock(2)
+func unlockFile(f *os.File) {}
@@ -0,0 +1,67 @@
+package autocd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that the reusable script is rewritten in place
+func TestWriteReusableScript_StablePath(t *testing.T) {
+	tempDir := t.TempDir()
+
+	first, release1, err := writeReusableScript("echo first\n", tempDir, "app")
+	if err != nil {
+		t.Fatalf("writeReusableScript failed: %v", err)
+	}
+	defer release1()
+
+	second, release2, err := writeReusableScript("echo second\n", tempDir, "app")
+	if err != nil {
+		t.Fatalf("writeReusableScript failed: %v", err)
+	}
+	defer release2()
+
+	if !strings.HasPrefix(first, "/dev/fd/") || !strings.HasPrefix(second, "/dev/fd/") {
+		t.Fatalf("Expected descriptor paths, got %s and %s", first, second)
+	}
+
+	scripts, _ := filepath.Glob(filepath.Join(tempDir, "autocd_*"))
+	if len(scripts) != 1 || scripts[0] != reusableScriptPath(tempDir, "app") {
+		t.Errorf("Expected a single stable script, got %v", scripts)
+	}
+
+	// Each descriptor stays pinned to the content written by its instance
+	firstContent, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("Failed to read pinned script: %v", err)
+	}
+	if string(firstContent) != "echo first\n" {
+		t.Errorf("First descriptor should keep its own content, got %q", firstContent)
+	}
+}
+
+// Test that cleanup removes old lock files unless a writer holds them
+func TestCleanup_ScriptLocks(t *testing.T) {
+	tempDir := t.TempDir()
+	lockPath := scriptLockPath(reusableScriptPath(tempDir, "app"))
+	old := time.Now().Add(-2 * time.Hour)
+
+	lock, err := lockReusableScript(lockPath)
+	if err != nil {
+		t.Fatalf("lockReusableScript failed: %v", err)
+	}
+	os.Chtimes(lockPath, old, old)
+	cleanupOldScriptsInDir(tempDir, time.Hour)
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("A held lock must not be removed: %v", err)
+	}
+	lock.Close()
+
+	cleanupOldScriptsInDir(tempDir, time.Hour)
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("An old unheld lock should be removed, got: %v", err)
+	}
+}
@@ -3,38 +3,287 @@ package autocd
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
+// defaultScriptMaxAge is how long after creation a transition script may
+// run (see Options.ScriptMaxAge)
+const defaultScriptMaxAge = 5 * time.Minute
+
+// scriptSections holds the pre-rendered, already escaped parts of a transition script
+type scriptSections struct {
+	TargetDir  string // Escaped target directory (without surrounding quotes)
+	ShellPath  string // Escaped shell path (without surrounding quotes)
+	Exports    string // Export statements for the inherited shell
+	ShellArgs  string // Quoted arguments appended to the exec line
+	ExecVia    string // Quoted command the shell is exec'd through (e.g. sudo), with trailing space
+	Terminal   string // Terminal escape sequences emitted after the cd
+	TargetURL  string // Escaped file:// URL for OSC 8 links in messages ("" = plain messages)
+	DisplayDir string // Escaped printable target for messages ("" = target has no control characters)
+	MarkStart  string // Semantic mark opening the transition output ("" = none)
+	MarkFinish string // Semantic mark closing the transition output ("" = none)
+	CreatedAt  int64  // Unix time the script was generated
+	MaxAge     int64  // Seconds after CreatedAt the script refuses to run (0 = no check)
+	Label      string // Escaped printf-safe "<app>: " prefix for messages ("" = none)
+	Command    string // Escaped command run after the cd, before the shell starts ("" = none)
+	ExitStatus int    // Status the script exits with instead of starting the shell without a terminal
+	ExitNoTTY  bool   // Exit with ExitStatus when stdin is not a terminal
+}
+
 // generateScript creates Unix shell script for directory transition
-func generateScript(targetDir string, shell *ShellInfo) (string, error) {
+func generateScript(targetDir string, shell *ShellInfo, opts *Options, launch *shellLaunch) (string, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if launch == nil {
+		launch = &shellLaunch{}
+	}
+
+	env := transitionEnvironment(targetDir, shell, opts, launch)
+	shellArgs := launchArgs(shell, opts, launch)
+
+	// Let fish, nushell or pwsh perform the cd itself once its configuration
+	// is loaded, so PWD hooks, prompt integrations and directory history see it
+	if opts.NativeCD {
+		shellArgs = append(shellArgs, nativeCDArgs(shell, targetDir)...)
+	}
+
+	// Hand the shell back to the user who ran the application via sudo
+	var execVia []string
+	if launch.RunAs != nil {
+		var err error
+		// ExtraEnv values travel through exec; sudo must still keep them
+		preserved := append(append([]envVar{}, env...), extraEnvironment(opts)...)
+		if execVia, err = sudoHandoff(launch.RunAs, preserved); err != nil {
+			return "", err
+		}
+	}
+
 	// Sanitize path for script injection prevention
-	safePath := sanitizePathForShell(targetDir)
-	safeShellPath := sanitizePathForShell(shell.Path)
+	sections := scriptSections{
+		TargetDir:  sanitizePathForShell(targetDir),
+		ShellPath:  sanitizePathForShell(shell.Path),
+		Exports:    renderExports(env),
+		ShellArgs:  renderShellArgs(shellArgs),
+		Terminal:   renderTerminalSequences(targetDir, opts),
+		CreatedAt:  time.Now().Unix(),
+		MaxAge:     int64(scriptMaxAge(opts) / time.Second),
+		Label:      messageLabel(opts),
+		Command:    sanitizePathForShell(opts.PostCommand),
+		ExitStatus: opts.AppExitStatus,
+		ExitNoTTY:  opts.ExitIfNonInteractive,
+	}
+	sections.MarkStart, sections.MarkFinish = renderSemanticMarks(opts)
+	if display := escapeControlChars(targetDir); display != targetDir {
+		sections.DisplayDir = sanitizePathForShell(display)
+	}
+	if escapesSupported(opts) {
+		sections.TargetURL = sanitizePathForShell(fileURL(targetDir))
+	}
+	if len(execVia) > 0 {
+		sections.ExecVia = strings.TrimPrefix(renderShellArgs(execVia), " ") + " "
+	}
 
 	// Generate Unix shell script
-	return generateUnixScript(safePath, safeShellPath), nil
+	return generateUnixScript(sections), nil
+}
+
+// messageLabel returns the "<app>: " prefix of script messages when the
+// application set Options.AppName, escaped for a single-quoted printf format
+func messageLabel(opts *Options) string {
+	if opts.AppName == "" {
+		return ""
+	}
+	label := invalidCharsRegex.ReplaceAllString(opts.AppName, "") + ": "
+	label = strings.NewReplacer(`\`, `\\`, "%", "%%").Replace(label)
+	return sanitizePathForShell(label)
+}
+
+// scriptMaxAge returns the freshness limit for scripts; 0 disables the check
+func scriptMaxAge(opts *Options) time.Duration {
+	switch {
+	case opts.ScriptMaxAge < 0:
+		return 0
+	case opts.ScriptMaxAge == 0:
+		return defaultScriptMaxAge
+	case opts.ScriptMaxAge < time.Second:
+		return time.Second
+	}
+	return opts.ScriptMaxAge
+}
+
+// nativeCDArgs returns the arguments making shell run its own cd into
+// targetDir after startup (nil for shells without a way to do so)
+func nativeCDArgs(shell *ShellInfo, targetDir string) []string {
+	shellType := shell.Type()
+	initCommand := shellType.Capabilities().InitCommand
+	if initCommand == nil {
+		return nil
+	}
+	return append(append([]string{}, initCommand...), "cd "+shellType.Quote(targetDir))
+}
+
+// transitionEnvironment lists every variable exported into the shell
+func transitionEnvironment(targetDir string, shell *ShellInfo, opts *Options, launch *shellLaunch) []envVar {
+	env := append(scriptEnvironment(targetDir, opts), shellEnvironment(shell, opts)...)
+	env = append(env, resetDepthEnvironment(shell, opts)...)
+	env = append(env, homeEnvironment()...)
+	return append(env, launch.Env...)
+}
+
+// launchArgs returns the arguments the shell is started with. Injected
+// long options (--rcfile) go first: bash rejects long options that follow
+// single-character ones such as -i.
+func launchArgs(shell *ShellInfo, opts *Options, launch *shellLaunch) []string {
+	args := append([]string{}, launch.Args...)
+	if opts.LoginShell && !launch.LoginRC {
+		args = append(args, "-l")
+	}
+	args = append(args, opts.ShellArgs...)
+	return append(args, shell.Args...)
 }
 
-func generateUnixScript(targetDir, shellPath string) string {
+func generateUnixScript(s scriptSections) string {
 	// Always use /bin/sh shebang since we execute with /bin/sh
 	shebang := "#!/bin/sh"
 
-	return fmt.Sprintf(`%s
+	link := s.TargetURL != ""
+	display := "$TARGET_DIR"
+	if s.DisplayDir != "" {
+		display = "$DISPLAY_DIR"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `%s
 # autocd transition script - auto-cleanup on exit
 TARGET_DIR='%s'
 SHELL_PATH='%s'
+`, shebang, s.TargetDir, s.ShellPath)
+	if s.DisplayDir != "" {
+		fmt.Fprintf(&b, "DISPLAY_DIR='%s'\n", s.DisplayDir)
+	}
+	if link {
+		fmt.Fprintf(&b, "TARGET_URL='%s'\n", s.TargetURL)
+	}
+
+	// A script passed as /dev/fd/N has been opened by now; close the
+	// inherited descriptor so it does not leak into the shell
+	b.WriteString(`case "${0#/dev/fd/}" in
+"$0" | "" | *[!0-9]*) ;;
+*) eval "exec ${0#/dev/fd/}<&-" ;;
+esac
+`)
+
+	if s.MaxAge > 0 {
+		b.WriteString(renderFreshnessCheck(s.CreatedAt, s.MaxAge))
+	}
+
+	if s.MarkStart != "" {
+		b.WriteString("\n# Mark the messages below as command output\n")
+		b.WriteString(s.MarkStart)
+	}
 
+	b.WriteString(`
 # Attempt to change directory with error handling
 if cd "$TARGET_DIR" 2>/dev/null; then
-    echo "Directory changed to: $TARGET_DIR"
-else
-    echo "Warning: Could not change to $TARGET_DIR" >&2
-    echo "Continuing in current directory" >&2
+`)
+	b.WriteString(renderPathMessage(s.Label+"Directory changed to: ", display, 1, link))
+	b.WriteString("else\n")
+	b.WriteString(renderPathMessage(s.Label+"Warning: Could not change to ", display, 2, link))
+	b.WriteString(`    echo "Continuing in current directory" >&2
+fi
+`)
+
+	if s.Terminal != "" {
+		b.WriteString("\n# Terminal integration (only when attached to a terminal)\n")
+		b.WriteString(s.Terminal)
+	}
+
+	b.WriteString("\n# Environment for the inherited shell\n")
+	b.WriteString(s.Exports)
+
+	if s.Command != "" {
+		fmt.Fprintf(&b, `
+# Run the application's command in the new directory, as the user the
+# shell runs as; the shell starts whatever its outcome
+RUN_COMMAND='%s'
+%s/bin/sh -c "$RUN_COMMAND"
+export AUTOCD_COMMAND_STATUS=$?
+`, s.Command, s.ExecVia)
+	}
+
+	if s.MarkFinish != "" {
+		b.WriteString("\n# End the output zone before the inherited shell's first prompt\n")
+		b.WriteString(s.MarkFinish)
+	}
+
+	if s.ExitNoTTY {
+		fmt.Fprintf(&b, `
+# Without a terminal nobody can use the shell; report the status instead
+if [ ! -t 0 ]; then
+    exit %d
 fi
+`, s.ExitStatus)
+	}
 
+	b.WriteString(`
 # Replace current process with shell
-exec "$SHELL_PATH"
-`, shebang, targetDir, shellPath)
+exec `)
+	b.WriteString(s.ExecVia)
+	b.WriteString(`"$SHELL_PATH"`)
+	b.WriteString(s.ShellArgs)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// renderFreshnessCheck refuses to run a script older than maxAge seconds,
+// so a stale script replayed from a shared temp directory does nothing.
+// Without a usable date(1) the check is skipped.
+func renderFreshnessCheck(createdAt, maxAge int64) string {
+	return fmt.Sprintf(`
+# Refuse to run a stale script
+CREATED_AT=%d
+NOW=$(date +%%s 2>/dev/null)
+case "$NOW" in
+    ''|*[!0-9]*) ;;
+    *)
+        if [ $((NOW - CREATED_AT)) -gt %d ]; then
+            echo "autocd: refusing to run a transition script created $((NOW - CREATED_AT))s ago" >&2
+            exit 1
+        fi
+        ;;
+esac
+`, createdAt, maxAge)
+}
+
+// renderPathMessage renders a message ending in the target directory as
+// shown by the display variable, written to fd. With link set, the
+// directory becomes an OSC 8 hyperlink to $TARGET_URL when fd is a terminal.
+func renderPathMessage(prefix, display string, fd int, link bool) string {
+	redirect := ""
+	if fd == 2 {
+		redirect = " >&2"
+	}
+	// printf, not echo: dash's echo expands backslashes found in the path
+	plain := fmt.Sprintf(`printf '%s%%s\n' "%s"%s`+"\n", prefix, display, redirect)
+	if !link {
+		return "    " + plain
+	}
+	return fmt.Sprintf("    if [ -t %d ]; then\n", fd) +
+		fmt.Sprintf(`        printf '%s\033]8;;%%s\033\\%%s\033]8;;\033\\\n' "$TARGET_URL" "%s"%s`+"\n", prefix, display, redirect) +
+		"    else\n" +
+		"        " + plain +
+		"    fi\n"
+}
+
+// renderShellArgs quotes each argument for the exec line
+func renderShellArgs(args []string) string {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(" ")
+		b.WriteString(shellQuote(arg))
+	}
+	return b.String()
 }
 
 // sanitizePathForShell prevents shell injection in Unix shells using single quotes
@@ -0,0 +1,50 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// Test that NewSession keeps the terminal: the new process group becomes
+// the foreground group. The check runs in a child process on a pty from
+// script(1), since it changes the process group.
+func TestStartNewSession_KeepsTerminal(t *testing.T) {
+	if os.Getenv("AUTOCD_TEST_NEW_SESSION") == "1" {
+		if err := startNewSession(&Options{}); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		defer tty.Close()
+		var pgid int32
+		syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgid)))
+		if int(pgid) == os.Getpid() && syscall.Getpgrp() == os.Getpid() {
+			fmt.Println("foreground group leader")
+		}
+		return
+	}
+
+	script, err := exec.LookPath("script")
+	if err != nil {
+		t.Skip("script not available")
+	}
+
+	// The trailing command keeps sh from exec'ing the test binary, which
+	// would then already lead its own group
+	command := shellQuote(os.Args[0]) + " -test.run='^TestStartNewSession_KeepsTerminal$'; :"
+	cmd := exec.Command(script, "-qec", command, "/dev/null")
+	cmd.Env = append(os.Environ(), "AUTOCD_TEST_NEW_SESSION=1")
+	output, _ := cmd.CombinedOutput()
+	if !strings.Contains(string(output), "foreground group leader") {
+		t.Errorf("Expected the new group to own the terminal, got:\n%s", output)
+	}
+}
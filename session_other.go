@@ -0,0 +1,14 @@
+//go:build !unix || aix || solaris
+
+package autocd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// startNewSession is not implemented where the syscall package lacks
+// setsid or getpgrp
+func startNewSession(opts *Options) error {
+	return fmt.Errorf("new sessions are not supported on %s", runtime.GOOS)
+}
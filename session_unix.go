@@ -0,0 +1,51 @@
+//go:build unix && !aix && !solaris
+
+package autocd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// startNewSession moves the process into a new process group and makes it
+// the terminal's foreground group, so the shell keeps job control, ^C and
+// ^Z. With Options.DetachSession it calls setsid instead; the new session
+// has no controlling terminal. A process that already leads its group
+// cannot call setsid (EPERM) and keeps its session.
+func startNewSession(opts *Options) error {
+	if opts.DetachSession {
+		_, err := syscall.Setsid()
+		if errors.Is(err, syscall.EPERM) {
+			err = syscall.Setpgid(0, 0)
+			if err == nil && opts.DebugMode {
+				fmt.Fprintf(os.Stderr, "autocd: already a process group leader, keeping session\n")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to start new session: %w", err)
+		}
+		return nil
+	}
+
+	if syscall.Getpgrp() != os.Getpid() {
+		if err := syscall.Setpgid(0, 0); err != nil {
+			return fmt.Errorf("failed to start new process group: %w", err)
+		}
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil // No controlling terminal to hand over
+	}
+	defer tty.Close()
+
+	// tcsetpgrp from a background group raises SIGTTOU
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	if err := setForegroundGroup(int(tty.Fd()), syscall.Getpgrp()); err != nil {
+		return fmt.Errorf("failed to move the new process group to the foreground: %w", err)
+	}
+	return nil
+}
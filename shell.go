@@ -1,23 +1,171 @@
 package autocd
 
 import (
+	"bufio"
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// defaultDetectionOrder is the detection sequence used when
+// Options.DetectionOrder is empty
+var defaultDetectionOrder = []DetectionSource{DetectOverride, DetectShellEnv, DetectPasswd, DetectFallback}
+
 // detectShell implements priority-based shell detection
 func detectShell(shellOverride string) *ShellInfo {
-	// 1. Explicit override (if provided)
+	return detectShellOrdered(shellOverride, nil)
+}
+
+// detectShellOrdered tries each detection tier in order and returns the
+// first shell found. A non-empty override always decides the result when
+// its tier is reached, even if it is invalid, since it was asked for
+// explicitly; leaving DetectOverride out of order ignores the override.
+func detectShellOrdered(shellOverride string, order []DetectionSource) *ShellInfo {
+	return detectShellAccepted(shellOverride, order, nil)
+}
+
+// detectShellAccepted is detectShellOrdered with an extra check: when
+// accept is non-nil, valid candidates it rejects (the override included)
+// fall through to the next tier
+func detectShellAccepted(shellOverride string, order []DetectionSource, accept func(*ShellInfo) bool) *ShellInfo {
+	if len(order) == 0 {
+		order = defaultDetectionOrder
+	}
+
+	var rejected *ShellInfo
+	for _, source := range order {
+		var shell *ShellInfo
+		if source == DetectOverride {
+			if shellOverride == "" {
+				continue
+			}
+			shell = validateShellOverride(shellOverride)
+			if !shell.IsValid {
+				return shell
+			}
+		} else if path := detectFromSource(source); path != "" && fileExists(path) {
+			shell = &ShellInfo{Path: path, IsValid: true}
+		} else {
+			continue
+		}
+
+		if accept == nil || accept(shell) {
+			return shell
+		}
+		if rejected == nil {
+			rejected = shell
+		}
+	}
+
+	if rejected != nil {
+		return &ShellInfo{Path: rejected.Path, IsValid: false}
+	}
+	return &ShellInfo{Path: "", IsValid: false}
+}
+
+// probeInteractiveShell runs the shell with -i -c 'exit 0' and reports
+// whether it exits successfully within timeout. Scripts, busybox applets
+// and wrappers that exec fine but cannot run interactively fail this.
+// The probe runs in its own session without a controlling terminal, so an
+// interactive shell cannot open /dev/tty and take the foreground away from
+// the application.
+func probeInteractiveShell(shell *ShellInfo, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, shell.Path, "-i", "-c", "exit 0")
+	// /dev/null for all three: the probe must not read the user's input or
+	// draw over the application's screen with its rc output
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	detachFromTerminal(cmd)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s did not finish an interactive probe within %s", shell.Path, timeout)
+		}
+		return fmt.Errorf("%s failed an interactive probe: %w", shell.Path, err)
+	}
+	return nil
+}
+
+// detectFromSource returns the candidate shell path from one tier ("" if none)
+func detectFromSource(source DetectionSource) string {
+	switch source {
+	case DetectParentProcess:
+		return parentProcessShell()
+	case DetectShellEnv:
+		return os.Getenv("SHELL")
+	case DetectPasswd:
+		return passwdShell()
+	case DetectFallback:
+		return "/bin/sh"
+	default:
+		return ""
+	}
+}
+
+// parentProcessShell returns the executable of the parent process when it
+// is a known shell, which is right even where SHELL is stale or wrong
+func parentProcessShell() string {
+	ppid := os.Getppid()
+	_, name, err := processParent(ppid)
+	if err != nil || !interactiveShellNames[shellName(name)] {
+		return ""
+	}
+
+	// Linux exposes the exact binary; elsewhere look the name up in PATH
+	if path, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", ppid)); err == nil {
+		return path
+	}
+	if path, err := exec.LookPath(shellName(name)); err == nil {
+		return path
+	}
+	return ""
+}
+
+// describeMissingShell explains a failed detection, naming what was
+// missing; SHELL and the passwd entry are often absent in cron jobs and
+// containers
+func describeMissingShell(shell *ShellInfo, shellOverride string) string {
 	if shellOverride != "" {
-		return validateShellOverride(shellOverride)
+		return fmt.Sprintf("shell override %q is not an executable file", shell.Path)
 	}
 
-	// 2. Environment variables (Unix)
-	return detectUnixShell()
+	var reasons []string
+	if env := os.Getenv("SHELL"); env == "" {
+		reasons = append(reasons, "SHELL is unset")
+	} else {
+		reasons = append(reasons, fmt.Sprintf("SHELL=%s is not executable", env))
+	}
+	if passwdShell() == "" {
+		reasons = append(reasons, fmt.Sprintf("no usable login shell in passwd for uid %d", os.Getuid()))
+	}
+	if !fileExists("/bin/sh") {
+		reasons = append(reasons, "/bin/sh is missing")
+	}
+	return "no valid shell found (" + strings.Join(reasons, ", ") + ")"
 }
 
 func validateShellOverride(shellOverride string) *ShellInfo {
+	// A command line such as "bash --noprofile -i" is split into the shell
+	// and its arguments, unless the whole string names an existing file
+	if strings.ContainsAny(shellOverride, " \t") && !fileExists(shellOverride) {
+		fields, err := splitCommandLine(shellOverride)
+		if err != nil || len(fields) == 0 {
+			return &ShellInfo{
+				Path:    shellOverride,
+				IsValid: false,
+			}
+		}
+		shell := validateShellOverride(fields[0])
+		shell.Args = fields[1:]
+		return shell
+	}
+
 	// Check if it's a shell name or full path
 	var shellPath string
 	if filepath.IsAbs(shellOverride) {
@@ -40,22 +188,177 @@ func validateShellOverride(shellOverride string) *ShellInfo {
 	}
 }
 
+// splitCommandLine splits a shell override into words using POSIX-like
+// rules: whitespace separates words, single quotes are literal, double
+// quotes allow backslash escapes. No expansion of any kind is performed.
+func splitCommandLine(line string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
 func detectUnixShell() *ShellInfo {
-	// Check SHELL environment variable
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		shell = "/bin/sh" // POSIX fallback
+	// SHELL, then the user's passwd entry, then the POSIX fallback
+	return detectShellOrdered("", []DetectionSource{DetectShellEnv, DetectPasswd, DetectFallback})
+}
+
+// defaultShellsFile lists the valid login shells on Unix systems
+const defaultShellsFile = "/etc/shells"
+
+// checkAllowedShell verifies that shellPath is listed in the shells file.
+// Entries are compared both literally and after resolving symlinks.
+func checkAllowedShell(shellPath, shellsFile string) error {
+	if shellsFile == "" {
+		shellsFile = defaultShellsFile
+	}
+
+	f, err := os.Open(shellsFile)
+	if err != nil {
+		return fmt.Errorf("%w: cannot read %s: %v", ErrShellNotAllowed, shellsFile, err)
 	}
+	defer f.Close()
 
-	// If SHELL is set but invalid, fallback to /bin/sh
-	if !fileExists(shell) {
-		shell = "/bin/sh"
+	resolved, err := filepath.EvalSymlinks(shellPath)
+	if err != nil {
+		resolved = shellPath
 	}
 
-	return &ShellInfo{
-		Path:    shell,
-		IsValid: fileExists(shell),
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if entry == shellPath || entry == resolved {
+			return nil
+		}
+		if real, err := filepath.EvalSymlinks(entry); err == nil && real == resolved {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: cannot read %s: %v", ErrShellNotAllowed, shellsFile, err)
 	}
+
+	return fmt.Errorf("%w: %s is not listed in %s", ErrShellNotAllowed, shellPath, shellsFile)
+}
+
+// passwdFile is the local user database consulted for login shells
+var passwdFile = "/etc/passwd"
+
+// passwdShell returns the login shell from the current user's passwd entry
+func passwdShell() string {
+	return passwdShellFor(os.Getuid())
+}
+
+// refusingLoginShells are passwd shells that exist only to deny logins,
+// standard for service accounts and container users
+var refusingLoginShells = map[string]bool{"nologin": true, "false": true, "true": true}
+
+// passwdShellFor returns the login shell of the user with the given uid,
+// or "" when the entry refuses logins
+func passwdShellFor(id int) string {
+	shell := passwdField(id, passwdShellField)
+	if refusingLoginShells[shellName(shell)] {
+		return ""
+	}
+	return shell
+}
+
+// passwdHomeFor returns the home directory of the user with the given uid
+func passwdHomeFor(id int) string {
+	return passwdField(id, passwdHomeField)
+}
+
+// Field indexes of name:pw:uid:gid:gecos:home:shell
+const (
+	passwdHomeField  = 5
+	passwdShellField = 6
+)
+
+// passwdField returns one field of the passwd entry for uid, reading the
+// local passwd file first and then getent (NSS, LDAP, etc.)
+func passwdField(id, index int) string {
+	uid := strconv.Itoa(id)
+
+	if data, err := os.ReadFile(passwdFile); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if value, ok := passwdEntryField(line, uid, index); ok {
+				return value
+			}
+		}
+	}
+
+	if out, err := exec.Command("getent", "passwd", uid).Output(); err == nil {
+		if value, ok := passwdEntryField(strings.TrimSpace(string(out)), uid, index); ok {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// passwdEntryField parses name:pw:uid:gid:gecos:home:shell, returning the
+// non-empty field at index when the entry belongs to uid
+func passwdEntryField(line, uid string, index int) (string, bool) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 7 || fields[2] != uid || fields[index] == "" {
+		return "", false
+	}
+	return fields[index], true
+}
+
+// shellName returns the base name of a shell path, ignoring the leading
+// dash used by login shells (e.g. "-bash" -> "bash")
+func shellName(shellPath string) string {
+	return strings.TrimPrefix(filepath.Base(shellPath), "-")
 }
 
 // fileExists checks if a file exists and is executable
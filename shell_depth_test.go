@@ -1,7 +1,10 @@
 package autocd
 
 import (
+	"errors"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -134,6 +137,53 @@ func TestCheckShellDepth_Unix(t *testing.T) {
 	}
 }
 
+// Test rate limiting of the depth tip across the transitions of a session
+func TestDepthTipRateLimit(t *testing.T) {
+	every3 := &Options{DepthWarningInterval: 3}
+	once := &Options{DepthWarningOnce: true}
+
+	// Simulate a session of transitions, all above the threshold
+	for _, tt := range []struct {
+		name string
+		opts *Options
+		want string // Shown (+) or skipped (-) per transition
+	}{
+		{"unlimited", &Options{}, "+++++"},
+		{"every_3", every3, "+--+-"},
+		{"once", once, "+----"},
+	} {
+		marker, got := "", ""
+		for i := 0; i < len(tt.want); i++ {
+			shown := depthTipDue(marker, tt.opts)
+			if shown {
+				got += "+"
+			} else {
+				got += "-"
+			}
+			marker = nextDepthTipMarker(marker, shown)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	// The marker is exported only when rate limiting is configured
+	originalShlvl, hadShlvl := os.LookupEnv("SHLVL")
+	defer restoreEnv("SHLVL", originalShlvl, hadShlvl)
+	os.Setenv("SHLVL", "20")
+	originalStderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() { os.Stderr = originalStderr }()
+
+	if vars := checkShellDepth(&Options{DepthWarningThreshold: 15}); vars != nil {
+		t.Errorf("No marker expected without rate limiting, got %v", vars)
+	}
+	vars := checkShellDepth(&Options{DepthWarningThreshold: 15, DepthWarningOnce: true})
+	if len(vars) != 1 || vars[0] != (envVar{Name: depthTipEnv, Value: "0"}) {
+		t.Errorf("Expected a fresh marker after showing the tip, got %v", vars)
+	}
+}
+
 // Test Options struct defaults for shell depth fields
 func TestOptions_ShellDepthDefaults(t *testing.T) {
 	tests := []struct {
@@ -272,3 +322,136 @@ func BenchmarkCheckShellDepth(b *testing.B) {
 		checkShellDepth(opts)
 	}
 }
+
+// Test ShellDepth prefers a valid SHLVL and falls back to the process tree
+func TestShellDepth(t *testing.T) {
+	originalShlvl, had := os.LookupEnv("SHLVL")
+	defer restoreEnv("SHLVL", originalShlvl, had)
+
+	os.Setenv("SHLVL", "7")
+	if depth := ShellDepth(); depth != 7 {
+		t.Errorf("Expected depth 7 from SHLVL, got %d", depth)
+	}
+
+	for _, value := range []string{"", "invalid", "0", "-3"} {
+		os.Setenv("SHLVL", value)
+		expected := processTreeDepth(os.Getppid())
+		if depth := ShellDepth(); depth != expected {
+			t.Errorf("SHLVL=%q: expected process tree depth %d, got %d", value, expected, depth)
+		}
+	}
+}
+
+// Test that a shell in the ancestry adds one nesting level
+func TestProcessTreeDepth_CountsShells(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// The trailing command keeps sh from exec'ing sleep directly
+	cmd := exec.Command("sh", "-c", "sleep 5; :")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sh: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	ppid, name, err := processParent(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("processParent failed: %v", err)
+	}
+	if ppid != os.Getpid() {
+		t.Errorf("Expected parent %d, got %d", os.Getpid(), ppid)
+	}
+	if shellName(name) != "sh" {
+		t.Errorf("Expected process name sh, got %q", name)
+	}
+
+	base := processTreeDepth(os.Getpid())
+	if depth := processTreeDepth(cmd.Process.Pid); depth != base+1 {
+		t.Errorf("Expected depth %d, got %d", base+1, depth)
+	}
+}
+
+// Test that ResetShellDepth starts the spawned shell at depth 1
+func TestResetShellDepth(t *testing.T) {
+	if env := resetDepthEnvironment(&ShellInfo{Path: "/bin/dash"}, &Options{ResetShellDepth: true}); len(env) != 1 || env[0].Value != "1" {
+		t.Errorf("Expected SHLVL=1 for shells that leave it alone, got %v", env)
+	}
+	if env := resetDepthEnvironment(&ShellInfo{Path: "/bin/bash"}, &Options{}); env != nil {
+		t.Errorf("Expected SHLVL untouched by default, got %v", env)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	shell := &ShellInfo{Path: bash, IsValid: true, Args: []string{"-c", `echo "$SHLVL"`}}
+	script, err := generateScript(t.TempDir(), shell, &Options{ResetShellDepth: true, PlainOutput: true}, nil)
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+	cmd := exec.Command("/bin/sh", "-c", script)
+	cmd.Env = append(os.Environ(), "SHLVL=9")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if !strings.HasSuffix(string(output), "\n1\n") {
+		t.Errorf("Expected bash at SHLVL 1, got:\n%s", output)
+	}
+}
+
+// Test blocking and collapsing transitions past the hard depth limit
+func TestDepthAction(t *testing.T) {
+	originalShlvl, hadShlvl := os.LookupEnv("SHLVL")
+	originalState, hadState := os.LookupEnv("XDG_STATE_HOME")
+	defer func() {
+		restoreEnv("SHLVL", originalShlvl, hadShlvl)
+		restoreEnv("XDG_STATE_HOME", originalState, hadState)
+	}()
+	os.Setenv("SHLVL", "12")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	target := t.TempDir()
+
+	_, _, err := GenerateTransitionScript(target, &Options{DepthAction: DepthActionBlock, DepthLimit: 12})
+	if err != nil {
+		t.Errorf("Only exiting transitions should be limited, got: %v", err)
+	}
+
+	// Neither action may get as far as replacing the test process
+	stubExecve(t, func(string, []string, []string) error {
+		t.Fatal("execve must not be reached past the depth limit")
+		return nil
+	})
+	exitCode := -1
+	originalExit := exitProcess
+	exitProcess = func(code int) { exitCode = code; panic("exit") }
+	defer func() { exitProcess = originalExit }()
+
+	func() {
+		defer func() { recover() }()
+		err = ExitWithDirectoryAdvanced(target, &Options{DepthAction: DepthActionBlock, DepthLimit: 12, DisableDepthWarnings: true})
+	}()
+	var autoErr *AutoCDError
+	if exitCode != -1 || !errors.Is(err, ErrDepthLimit) || !errors.As(err, &autoErr) || autoErr.Type != ErrorDepthLimit {
+		t.Errorf("Expected ErrDepthLimit, got: %v (exit %d)", err, exitCode)
+	}
+
+	func() {
+		defer func() { recover() }()
+		ExitWithDirectoryAdvanced(target, &Options{DepthAction: DepthActionCollapse, DepthLimit: 10, AppExitStatus: 2, DisableDepthWarnings: true})
+	}()
+	if exitCode != 2 {
+		t.Errorf("Expected exit with the application status 2, got %d", exitCode)
+	}
+	if DepthStateFile() != filepath.Join(os.Getenv("XDG_STATE_HOME"), "autocd", "dir") {
+		t.Errorf("Unexpected state file %s", DepthStateFile())
+	}
+	content, err := os.ReadFile(DepthStateFile())
+	if err != nil || string(content) != target {
+		t.Errorf("Expected %s in the state file, got %q (%v)", target, content, err)
+	}
+}
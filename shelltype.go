@@ -0,0 +1,163 @@
+package autocd
+
+import "strings"
+
+// ShellType classifies a shell by the language it speaks
+type ShellType int
+
+const (
+	ShellUnknown    ShellType = iota // Not recognized
+	ShellSh                          // POSIX sh and close relatives: sh, ash, yash
+	ShellBash                        // bash
+	ShellZsh                         // zsh
+	ShellFish                        // fish
+	ShellDash                        // dash
+	ShellKsh                         // ksh, ksh93, mksh
+	ShellTcsh                        // tcsh, csh
+	ShellPowerShell                  // pwsh, powershell
+	ShellNushell                     // nu
+	ShellCmd                         // cmd.exe
+	ShellElvish                      // elvish
+	ShellOSH                         // osh (Oils in bash-compatible mode)
+	ShellYSH                         // ysh, oil
+	ShellMurex                       // murex
+)
+
+// shellTypesByName maps executable names to shell types. It is the single
+// list of shells autocd knows: depth counting, parent-shell detection and
+// rc injection all derive from it.
+var shellTypesByName = map[string]ShellType{
+	"sh": ShellSh, "ash": ShellSh, "yash": ShellSh,
+	"bash": ShellBash, "zsh": ShellZsh, "fish": ShellFish, "dash": ShellDash,
+	"ksh": ShellKsh, "ksh93": ShellKsh, "mksh": ShellKsh,
+	"tcsh": ShellTcsh, "csh": ShellTcsh,
+	"pwsh": ShellPowerShell, "pwsh-preview": ShellPowerShell, "powershell": ShellPowerShell,
+	"powershell.exe": ShellPowerShell, "nu": ShellNushell,
+	"cmd": ShellCmd, "cmd.exe": ShellCmd,
+	"elvish": ShellElvish, "osh": ShellOSH, "ysh": ShellYSH, "oil": ShellYSH,
+	"murex": ShellMurex,
+}
+
+// ShellCapabilities describes what autocd can rely on for a shell type
+type ShellCapabilities struct {
+	POSIX           bool     // Runs POSIX sh scripts (and the transition script)
+	IncrementsSHLVL bool     // Raises an inherited SHLVL by one at startup
+	InitCommand     []string // Arguments preceding a command run at startup in the interactive shell (nil = none)
+}
+
+// ClassifyShell returns the type of the shell at shellPath, judged by its
+// executable name ("-bash" login names included)
+func ClassifyShell(shellPath string) ShellType {
+	return shellTypesByName[shellName(shellPath)]
+}
+
+// Type returns the classification of the shell
+func (s *ShellInfo) Type() ShellType {
+	return ClassifyShell(s.Path)
+}
+
+// String returns the shell type's usual executable name
+func (t ShellType) String() string {
+	switch t {
+	case ShellSh:
+		return "sh"
+	case ShellBash:
+		return "bash"
+	case ShellZsh:
+		return "zsh"
+	case ShellFish:
+		return "fish"
+	case ShellDash:
+		return "dash"
+	case ShellKsh:
+		return "ksh"
+	case ShellTcsh:
+		return "tcsh"
+	case ShellPowerShell:
+		return "pwsh"
+	case ShellNushell:
+		return "nu"
+	case ShellCmd:
+		return "cmd"
+	case ShellElvish:
+		return "elvish"
+	case ShellOSH:
+		return "osh"
+	case ShellYSH:
+		return "ysh"
+	case ShellMurex:
+		return "murex"
+	default:
+		return "unknown"
+	}
+}
+
+// Capabilities returns the features autocd uses for the shell type
+func (t ShellType) Capabilities() ShellCapabilities {
+	switch t {
+	case ShellSh, ShellDash, ShellKsh, ShellOSH:
+		return ShellCapabilities{POSIX: true}
+	case ShellBash, ShellZsh:
+		return ShellCapabilities{POSIX: true, IncrementsSHLVL: true}
+	case ShellFish:
+		return ShellCapabilities{IncrementsSHLVL: true, InitCommand: []string{"--init-command"}}
+	case ShellTcsh:
+		return ShellCapabilities{IncrementsSHLVL: true}
+	case ShellPowerShell:
+		return ShellCapabilities{InitCommand: []string{"-NoExit", "-Command"}}
+	case ShellNushell:
+		return ShellCapabilities{IncrementsSHLVL: true, InitCommand: []string{"--execute"}}
+	default:
+		return ShellCapabilities{}
+	}
+}
+
+// Quote returns value as a single word in the shell's syntax. Unknown
+// shells get POSIX quoting.
+func (t ShellType) Quote(value string) string {
+	switch t {
+	case ShellFish:
+		return fishQuote(value)
+	case ShellNushell:
+		return nuQuote(value)
+	case ShellPowerShell:
+		return pwshQuote(value)
+	case ShellYSH:
+		return yshQuote(value)
+	case ShellElvish:
+		return elvishQuote(value)
+	case ShellCmd:
+		return cmdQuote(value)
+	default:
+		return shellQuote(value)
+	}
+}
+
+// pwshQuote wraps a value in single quotes for PowerShell, which doubles
+// embedded quotes and also treats the typographic single quotes as quotes
+func pwshQuote(value string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range value {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// elvishQuote wraps a value in single quotes for elvish, which takes no
+// escapes there and writes a literal quote as two quotes
+func elvishQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// cmdQuote wraps a value in double quotes for cmd.exe, doubling embedded
+// quotes. Percent signs still expand inside quotes; cmd has no escape for
+// them on the command line.
+func cmdQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
@@ -0,0 +1,88 @@
+package autocd
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// Test shell classification and capabilities
+func TestClassifyShell(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected ShellType
+	}{
+		{"/bin/bash", ShellBash},
+		{"-zsh", ShellZsh},
+		{"/usr/local/bin/fish", ShellFish},
+		{"/bin/mksh", ShellKsh},
+		{"/bin/csh", ShellTcsh},
+		{"/usr/bin/pwsh", ShellPowerShell},
+		{"/bin/sh", ShellSh},
+		{"/usr/bin/elvish", ShellElvish},
+		{"/usr/local/bin/ysh", ShellYSH},
+		{"/usr/bin/osh", ShellOSH},
+		{"/usr/bin/murex", ShellMurex},
+		{"cmd.exe", ShellCmd},
+		{"/usr/bin/xonsh", ShellUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyShell(tt.path); got != tt.expected {
+			t.Errorf("ClassifyShell(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+
+	if !ShellDash.Capabilities().POSIX || ShellFish.Capabilities().POSIX {
+		t.Error("Expected dash to be POSIX and fish not")
+	}
+	if !ShellOSH.Capabilities().POSIX || ShellYSH.Capabilities().POSIX {
+		t.Error("Expected osh to be POSIX and ysh not")
+	}
+	if got := ShellElvish.Quote(`it's $HOME\n`); got != `'it''s $HOME\n'` {
+		t.Errorf("Unexpected elvish quoting: %s", got)
+	}
+	if got := ShellCmd.Quote(`say "hi"`); got != `"say ""hi"""` {
+		t.Errorf("Unexpected cmd quoting: %s", got)
+	}
+	if (&ShellInfo{Path: "/bin/zsh"}).Type() != ShellZsh {
+		t.Error("Expected ShellInfo.Type to classify its path")
+	}
+}
+
+// Test that the ancestry walk counts every classified shell but cmd.exe
+func TestInteractiveShellNames(t *testing.T) {
+	for name, shellType := range shellTypesByName {
+		if interactiveShellNames[name] != (shellType != ShellCmd) {
+			t.Errorf("%s (%v): interactive = %v", name, shellType, interactiveShellNames[name])
+		}
+	}
+	if len(interactiveShellNames) == 0 || interactiveShellNames["xonsh"] {
+		t.Error("Unexpected interactive shell names")
+	}
+}
+
+// Test that quoted values survive each shell's parser
+func TestShellType_Quote(t *testing.T) {
+	value := `it's a "dir" with $HOME and \back`
+	if got := ShellPowerShell.Quote("it's ’x"); got != "'it''s ’’x'" {
+		t.Errorf("Unexpected PowerShell quoting: %s", got)
+	}
+
+	for _, shellType := range []ShellType{ShellSh, ShellBash, ShellFish, ShellPowerShell} {
+		shell, err := exec.LookPath(shellType.String())
+		if err != nil {
+			continue
+		}
+		args := []string{"-c", `printf '%s\n' ` + shellType.Quote(value)}
+		if shellType == ShellPowerShell {
+			args = []string{"-NoProfile", "-Command", "Write-Output " + shellType.Quote(value)}
+		}
+		output, err := exec.Command(shell, args...).Output()
+		if err != nil {
+			t.Errorf("%v failed: %v", shellType, err)
+			continue
+		}
+		if string(output) != value+"\n" {
+			t.Errorf("%v: expected %q, got %q", shellType, value, output)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sudoInvoker is the user who started the application through sudo
+type sudoInvoker struct {
+	Name string // SUDO_USER
+	UID  int    // SUDO_UID
+	GID  int    // SUDO_GID
+}
+
+// geteuid reports the effective user ID (replaceable in tests)
+var geteuid = os.Geteuid
+
+// detectSudoInvoker returns the original user when the process runs as root
+// through sudo, or nil when it doesn't (or sudo's variables are incomplete)
+func detectSudoInvoker() *sudoInvoker {
+	if geteuid() != 0 {
+		return nil
+	}
+
+	name := os.Getenv("SUDO_USER")
+	if name == "" || name == "root" {
+		return nil
+	}
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil || uid == 0 {
+		return nil
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		return nil
+	}
+	return &sudoInvoker{Name: name, UID: uid, GID: gid}
+}
+
+// owns reports whether path belongs to the invoking user
+func (s *sudoInvoker) owns(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	uid, _, ok := fileOwner(info)
+	return ok && uid == s.UID
+}
+
+// loginShell returns the invoking user's shell from passwd, or nil when it
+// cannot be determined
+func (s *sudoInvoker) loginShell() *ShellInfo {
+	if shell := passwdShellFor(s.UID); shell != "" && fileExists(shell) {
+		return &ShellInfo{Path: shell, IsValid: true}
+	}
+	return nil
+}
+
+// chown hands the launch artifacts (rc files and directories) to the
+// invoking user so the unprivileged shell can read them
+func (l *shellLaunch) chown(uid, gid int) error {
+	for _, artifact := range l.Artifacts {
+		err := filepath.Walk(artifact, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, uid, gid)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to hand rc files to %s: %w", artifact, err)
+		}
+	}
+	return nil
+}
+
+// sudoHandoff returns the sudo command line that starts the shell as the
+// invoking user. The transition script has already changed directory,
+// which sudo keeps (unlike "sudo -i"); the exported variables and
+// Options.ExtraEnv are listed in --preserve-env so sudo's env_reset
+// doesn't drop them. Only names appear on the command line.
+func sudoHandoff(invoker *sudoInvoker, env []envVar) ([]string, error) {
+	sudoPath, err := exec.LookPath("sudo")
+	if err != nil {
+		return nil, fmt.Errorf("sudo handoff requested but sudo was not found: %w", err)
+	}
+
+	names := make([]string, 0, len(env))
+	seen := map[string]bool{}
+	for _, v := range env {
+		if !seen[v.Name] {
+			names = append(names, v.Name)
+			seen[v.Name] = true
+		}
+	}
+	sort.Strings(names)
+
+	args := []string{sudoPath, "-u", invoker.Name, "-H"}
+	if len(names) > 0 {
+		args = append(args, "--preserve-env="+strings.Join(names, ","))
+	}
+	return append(args, "--"), nil
+}
+
+// warnRootShell explains that the inherited shell will run as root,
+// pointing out the common sudo case of a root shell in a user's directory
+func warnRootShell(targetDir string, invoker *sudoInvoker, opts *Options) {
+	shown := displayPath(targetDir, 2, opts)
+	if invoker != nil && invoker.owns(targetDir) {
+		fmt.Fprintf(os.Stderr, "autocd: warning: spawning a root shell in %s, which belongs to %s (started via sudo)\n", shown, invoker.Name)
+		fmt.Fprintf(os.Stderr, "Files created there will be owned by root.\n")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "autocd: warning: spawning a root shell in %s\n", shown)
+}
@@ -0,0 +1,140 @@
+package autocd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubRoot pretends the process runs as root for the duration of a test
+func stubRoot(t *testing.T) {
+	original := geteuid
+	geteuid = func() int { return 0 }
+	t.Cleanup(func() { geteuid = original })
+}
+
+// Test SUDO_USER/SUDO_UID/SUDO_GID detection
+func TestDetectSudoInvoker(t *testing.T) {
+	stubRoot(t)
+	t.Setenv("SUDO_USER", "alice")
+	t.Setenv("SUDO_UID", "1000")
+	t.Setenv("SUDO_GID", "1001")
+
+	invoker := detectSudoInvoker()
+	if invoker == nil {
+		t.Fatal("Expected a sudo invoker")
+	}
+	if invoker.Name != "alice" || invoker.UID != 1000 || invoker.GID != 1001 {
+		t.Errorf("Unexpected invoker: %+v", invoker)
+	}
+
+	t.Setenv("SUDO_USER", "root")
+	if detectSudoInvoker() != nil {
+		t.Error("sudo from root should not count as an invoker")
+	}
+
+	t.Setenv("SUDO_USER", "alice")
+	t.Setenv("SUDO_UID", "not-a-number")
+	if detectSudoInvoker() != nil {
+		t.Error("Malformed SUDO_UID should be ignored")
+	}
+}
+
+// Test that the invoker is only reported when running as root
+func TestDetectSudoInvoker_NotRoot(t *testing.T) {
+	original := geteuid
+	geteuid = func() int { return 1000 }
+	defer func() { geteuid = original }()
+
+	t.Setenv("SUDO_USER", "alice")
+	t.Setenv("SUDO_UID", "1000")
+	t.Setenv("SUDO_GID", "1000")
+	if detectSudoInvoker() != nil {
+		t.Error("Non-root process should not hand off to SUDO_USER")
+	}
+}
+
+// Test the sudo command line preserving exported variables
+func TestSudoHandoff(t *testing.T) {
+	binDir := t.TempDir()
+	sudoPath := filepath.Join(binDir, "sudo")
+	os.WriteFile(sudoPath, []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", binDir)
+
+	env := []envVar{{Name: "AUTOCD_TARGET_DIR"}, {Name: "ENV"}, {Name: "AUTOCD_TARGET_DIR"}}
+	args, err := sudoHandoff(&sudoInvoker{Name: "alice", UID: 1000, GID: 1000}, env)
+	if err != nil {
+		t.Fatalf("sudoHandoff failed: %v", err)
+	}
+
+	expected := []string{sudoPath, "-u", "alice", "-H", "--preserve-env=AUTOCD_TARGET_DIR,ENV", "--"}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := sudoHandoff(&sudoInvoker{Name: "alice"}, env); err == nil {
+		t.Error("Expected an error when sudo is not installed")
+	}
+}
+
+// Test that a handed-off script execs the shell through sudo
+func TestGenerateScript_SudoHandoff(t *testing.T) {
+	binDir := t.TempDir()
+	os.WriteFile(filepath.Join(binDir, "sudo"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", binDir)
+
+	shell := &ShellInfo{Path: "/bin/bash", IsValid: true}
+	launch := &shellLaunch{RunAs: &sudoInvoker{Name: "alice", UID: 1000, GID: 1000}}
+	script, err := generateScript("/tmp", shell, &Options{}, launch)
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+
+	if !strings.Contains(script, "'-u' 'alice' '-H'") || !strings.Contains(script, `'--' "$SHELL_PATH"`) {
+		t.Errorf("Script should exec the shell through sudo:\n%s", script)
+	}
+	if !strings.Contains(script, "AUTOCD_TARGET_DIR") {
+		t.Errorf("Script should preserve autocd variables:\n%s", script)
+	}
+
+	script, err = generateScript("/tmp", shell, &Options{ExtraEnv: map[string]string{"API_TOKEN": "s3cret"}}, launch)
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+	if !strings.Contains(script, "--preserve-env=API_TOKEN,") || strings.Contains(script, "s3cret") {
+		t.Errorf("ExtraEnv names should be preserved through sudo without their values:\n%s", script)
+	}
+
+	script, err = generateScript("/tmp", shell, &Options{PostCommand: "touch owned"}, launch)
+	if err != nil {
+		t.Fatalf("generateScript failed: %v", err)
+	}
+	if !strings.Contains(script, `'--' /bin/sh -c "$RUN_COMMAND"`) {
+		t.Errorf("PostCommand should run through sudo as the invoking user:\n%s", script)
+	}
+
+	plain, _ := generateScript("/tmp", shell, &Options{}, nil)
+	if !strings.Contains(plain, "\nexec \"$SHELL_PATH\"\n") {
+		t.Errorf("Script without handoff should exec the shell directly:\n%s", plain)
+	}
+}
+
+// Test handing rc artifacts to another owner (the current user here)
+func TestShellLaunchChown(t *testing.T) {
+	dir := t.TempDir()
+	rcDir := filepath.Join(dir, "autocd_rc_test")
+	os.Mkdir(rcDir, 0700)
+	os.WriteFile(filepath.Join(rcDir, ".zshrc"), []byte("\n"), 0600)
+
+	launch := &shellLaunch{Artifacts: []string{rcDir}}
+	if err := launch.chown(os.Getuid(), os.Getgid()); err != nil {
+		t.Errorf("chown to the current user should succeed: %v", err)
+	}
+
+	launch.Artifacts = append(launch.Artifacts, filepath.Join(dir, "missing"))
+	if err := launch.chown(os.Getuid(), os.Getgid()); err == nil {
+		t.Error("Expected an error for a missing artifact")
+	}
+}
@@ -0,0 +1,55 @@
+package autocd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// SupportInfo explains whether autocd can work in the current environment
+type SupportInfo struct {
+	Supported       bool       // Whether ExitWithDirectory is expected to work
+	Platform        string     // GOOS/GOARCH, e.g. "linux/amd64"
+	Shell           *ShellInfo // Detected shell (IsValid false if none found)
+	POSIXShell      bool       // Whether /bin/sh, which runs the transition script, exists
+	TempDir         string     // Directory transition scripts are written to
+	TempDirWritable bool       // Whether scripts can be created in TempDir
+	Terminal        bool       // Whether a controlling terminal and TTY stdio are present
+	IDETerminal     string     // IDE integrated terminal autocd adapts to ("vscode", "jetbrains", "" = none)
+	Reasons         []string   // Human-readable explanations for each failed check
+}
+
+// SupportReport performs the checks behind IsSupported and a few more,
+// recording why each one failed, so applications can decide whether to
+// offer "exit to directory" and explain its absence to the user
+func SupportReport() *SupportInfo {
+	report := &SupportInfo{
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		Shell:       detectShell(""),
+		TempDir:     systemTempDir(),
+		IDETerminal: ideTerminal(),
+	}
+
+	if !report.Shell.IsValid {
+		report.Reasons = append(report.Reasons, "no valid shell found")
+	}
+
+	report.POSIXShell = fileExists("/bin/sh")
+	if !report.POSIXShell {
+		report.Reasons = append(report.Reasons, "/bin/sh is missing or not executable")
+	}
+
+	if err := checkAccess(report.TempDir, accessWrite|accessExecute); err != nil {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("temp dir %s is not writable: %v", report.TempDir, err))
+	} else {
+		report.TempDirWritable = true
+	}
+
+	if err := checkTerminal(false, false); err != nil {
+		report.Reasons = append(report.Reasons, err.Error())
+	} else {
+		report.Terminal = true
+	}
+
+	report.Supported = len(report.Reasons) == 0
+	return report
+}
@@ -0,0 +1,60 @@
+package autocd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Test that SupportReport is consistent with its individual checks
+func TestSupportReport(t *testing.T) {
+	report := SupportReport()
+
+	if report.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("Unexpected platform: %s", report.Platform)
+	}
+	if report.Shell == nil {
+		t.Fatal("Expected shell info to be set")
+	}
+	if report.Shell.IsValid != IsSupported() {
+		t.Errorf("Shell validity %v disagrees with IsSupported", report.Shell.IsValid)
+	}
+	if report.Supported != (len(report.Reasons) == 0) {
+		t.Errorf("Supported=%v but reasons: %v", report.Supported, report.Reasons)
+	}
+	if report.Supported && !(report.Shell.IsValid && report.POSIXShell && report.TempDirWritable && report.Terminal) {
+		t.Errorf("Report claims support with a failed check: %+v", report)
+	}
+}
+
+// Test that an unusable temp dir is reported with a reason
+func TestSupportReport_UnwritableTempDir(t *testing.T) {
+	originalTmp, had := os.LookupEnv("TMPDIR")
+	defer restoreEnv("TMPDIR", originalTmp, had)
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	os.Setenv("TMPDIR", missing)
+
+	report := SupportReport()
+	if report.TempDir != missing {
+		t.Errorf("Expected temp dir %s, got %s", missing, report.TempDir)
+	}
+	if report.TempDirWritable {
+		t.Error("Expected missing temp dir to be reported as not writable")
+	}
+	if report.Supported {
+		t.Error("Expected report to be unsupported")
+	}
+
+	found := false
+	for _, reason := range report.Reasons {
+		if strings.Contains(reason, missing) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a reason naming %s, got: %v", missing, report.Reasons)
+	}
+}
@@ -0,0 +1,38 @@
+package autocd
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// userTempDir caches the per-user temp directory reported by getconf
+var userTempDir struct {
+	once sync.Once
+	dir  string
+}
+
+// systemTempDir returns the default location for autocd files. Without
+// TMPDIR (sudo, launchd jobs) Go falls back to the shared /tmp; macOS
+// provides a private, periodically cleaned /var/folders directory per user
+// instead. confstr(_CS_DARWIN_USER_TEMP_DIR) needs cgo, so getconf is
+// asked once per process.
+func systemTempDir() string {
+	if os.Getenv("TMPDIR") != "" {
+		return os.TempDir()
+	}
+	userTempDir.once.Do(func() {
+		out, err := exec.Command("getconf", "DARWIN_USER_TEMP_DIR").Output()
+		if err != nil {
+			return
+		}
+		if dir := strings.TrimSpace(string(out)); dir != "" && DirectoryExists(dir) {
+			userTempDir.dir = dir
+		}
+	})
+	if userTempDir.dir != "" {
+		return userTempDir.dir
+	}
+	return os.TempDir()
+}
@@ -0,0 +1,10 @@
+//go:build !darwin
+
+package autocd
+
+import "os"
+
+// systemTempDir returns the default location for autocd files
+func systemTempDir() string {
+	return os.TempDir()
+}
@@ -9,18 +9,17 @@ import (
 	"time"
 )
 
-// createTemporaryScript writes script content to temp file
-func createTemporaryScript(content, extension string, tempDir string) (string, error) {
+// createTemporaryScript writes script content to temp file; app tags the
+// file name with the application it belongs to ("" = untagged)
+func createTemporaryScript(content, extension, tempDir, app string) (string, error) {
 	// Use custom temp dir or system default
 	if tempDir == "" {
-		tempDir = os.TempDir()
+		tempDir = systemTempDir()
 	}
 
-	// Create temporary file with proper prefix and extension
-	pattern := "autocd_*" + extension
-	tmpFile, err := os.CreateTemp(tempDir, pattern)
+	tmpFile, err := os.CreateTemp(tempDir, artifactPattern(app, extension))
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		return "", fmt.Errorf("failed to create temp file in %s: %w", tempDir, err)
 	}
 	defer tmpFile.Close()
 
@@ -40,10 +39,116 @@ func createTemporaryScript(content, extension string, tempDir string) (string, e
 	return tmpFile.Name(), nil
 }
 
+// artifactPattern returns the CreateTemp/MkdirTemp pattern for autocd
+// files: prefix, PID, creation time, application and extension. The PID
+// survives exec, so it identifies the spawned shell that owns the artifact;
+// together with the timestamp, cleanup can decide old-vs-new and
+// live-vs-dead from the name alone. The application name only tells
+// several autocd-enabled tools' files apart.
+func artifactPattern(app, extension string) string {
+	tag := ""
+	if app != "" {
+		tag = unsafeNameChars.ReplaceAllString(app, "_") + "-"
+	}
+	return fmt.Sprintf("autocd_%d_%d_%s*%s", os.Getpid(), time.Now().Unix(), tag, extension)
+}
+
+// writeScript stores the transition script according to the options and
+// returns the path to execute plus a release function for failure cleanup
+func writeScript(content string, opts *Options) (string, func(), error) {
+	// Setuid processes verify script ownership via lstat, which a /dev/fd
+	// path cannot satisfy, so they always use one-off files
+	tempDir := opts.TempDir
+	if opts.PreflightScriptDir && !isPrivileged() {
+		dir, err := firstRunnableDir(scriptDirCandidates(opts), opts.DebugMode)
+		if err != nil {
+			// No location can run scripts; keep the script in memory
+			if opts.DebugMode {
+				fmt.Fprintf(os.Stderr, "autocd: no runnable script directory, using a pipe\n")
+			}
+			return writePipedScript(content)
+		}
+		tempDir = dir
+	}
+
+	if opts.ReuseScript && !isPrivileged() {
+		return writeReusableScript(content, tempDir, appName(opts))
+	}
+
+	scriptPath, err := createTemporaryScript(content, ".sh", tempDir, appName(opts))
+	if err != nil {
+		return "", nil, err
+	}
+	return scriptPath, func() { os.Remove(scriptPath) }, nil
+}
+
+// defaultCleanupMaxAge is the age after which the sweep run by each
+// transition removes scripts (see Options.CleanupMaxAge)
+const defaultCleanupMaxAge = 1 * time.Hour
+
+// cleanupGracePeriod is how long exec waits for an unfinished background
+// sweep before abandoning it
+const cleanupGracePeriod = 20 * time.Millisecond
+
+// startCleanup sweeps old scripts from the system and custom temp
+// directories in a goroutine. The returned channel is closed when the
+// sweep is done (immediately when cleanup is disabled). Errors are
+// non-fatal and only reported in debug mode.
+func startCleanup(opts *Options) <-chan struct{} {
+	done := make(chan struct{})
+	if opts.DisableCleanup {
+		close(done)
+		return done
+	}
+
+	maxAge := opts.CleanupMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCleanupMaxAge
+	}
+	tempDir, debugMode := opts.TempDir, opts.DebugMode
+	runtimeDir := ""
+	if opts.PreflightScriptDir {
+		runtimeDir = os.Getenv("XDG_RUNTIME_DIR")
+	}
+
+	go func() {
+		defer close(done)
+		if err := cleanupOldScripts(maxAge); err != nil && debugMode {
+			fmt.Fprintf(os.Stderr, "autocd: cleanup warning: %v\n", err)
+		}
+
+		// If a custom temp dir is specified, clean it as well
+		if tempDir != "" && DirectoryExists(tempDir) {
+			if err := cleanupOldScriptsInDir(tempDir, maxAge); err != nil && debugMode {
+				fmt.Fprintf(os.Stderr, "autocd: cleanup (custom temp) warning: %v\n", err)
+			}
+		}
+
+		// The preflight fallback location collects scripts too
+		if runtimeDir != "" && DirectoryExists(runtimeDir) {
+			if err := cleanupOldScriptsInDir(runtimeDir, maxAge); err != nil && debugMode {
+				fmt.Fprintf(os.Stderr, "autocd: cleanup (runtime dir) warning: %v\n", err)
+			}
+		}
+	}()
+	return done
+}
+
+// waitCleanup waits for a background sweep for at most grace and reports
+// whether it finished
+func waitCleanup(done <-chan struct{}, grace time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(grace):
+		return false
+	}
+}
+
 // cleanupOldScripts removes old autocd scripts (optional cleanup)
 func cleanupOldScripts(maxAge time.Duration) error {
 	// Clean in default temp dir
-	return cleanupOldScriptsInDir(os.TempDir(), maxAge)
+	return cleanupOldScriptsInDir(systemTempDir(), maxAge)
 }
 
 // cleanupOldScriptsInDir removes old autocd scripts in a specific directory
@@ -55,21 +160,91 @@ func cleanupOldScriptsInDir(dir string, maxAge time.Duration) error {
 
 	cutoff := time.Now().Add(-maxAge)
 	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "autocd_") {
-			info, err := entry.Info()
-			if err != nil {
-				continue // Skip files we can't stat
+		name := entry.Name()
+		if strings.HasPrefix(name, "autocd.") && strings.HasSuffix(name, ".lock") {
+			if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+				removeScriptLock(filepath.Join(dir, name), info)
 			}
+			continue
+		}
+		if !strings.HasPrefix(name, "autocd_") {
+			continue
+		}
 
-			if info.ModTime().Before(cutoff) {
-				os.Remove(filepath.Join(dir, entry.Name()))
+		// Fast path: PID and creation time are encoded in the name, so only
+		// entries about to be removed are statted
+		if pid, created, ok := scriptNameInfo(name); ok {
+			if created.Before(cutoff) || !processAlive(pid) {
+				removeArtifact(dir, name, nil)
 			}
+			continue
+		}
+
+		// Older naming schemes rely on the modification time
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(cutoff) || isOrphanedScript(name) {
+			removeArtifact(dir, name, info)
 		}
 	}
 
 	return nil
 }
 
+// removeArtifact deletes a script or rc directory in dir. Other users'
+// files and anything autocd didn't write are never touched, whatever the
+// directory permissions would allow. info is the entry's lstat result, or
+// nil to look it up.
+func removeArtifact(dir, name string, info fs.FileInfo) {
+	path := filepath.Join(dir, name)
+	if info == nil {
+		var err error
+		if info, err = os.Lstat(path); err != nil {
+			return
+		}
+	}
+	switch {
+	case removableRCDir(name, info):
+		os.RemoveAll(path)
+	case removableScript(info):
+		os.Remove(path)
+	}
+}
+
+// removableScript reports whether cleanup may delete a file: a regular
+// file owned by the effective user, without setuid/setgid/sticky bits and
+// not writable by group or others, as autocd creates its scripts
+func removableScript(info fs.FileInfo) bool {
+	mode := info.Mode()
+	if !mode.IsRegular() || mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) != 0 || mode.Perm()&0022 != 0 {
+		return false
+	}
+	uid, _, ok := fileOwner(info)
+	return ok && uid == os.Geteuid()
+}
+
+// removableRCDir reports whether cleanup may delete a generated zsh rc
+// directory: private to the effective user and named like the scripts
+func removableRCDir(name string, info fs.FileInfo) bool {
+	if !info.IsDir() || !strings.HasSuffix(name, ".rc") || info.Mode()&(fs.ModeSymlink|fs.ModeSetgid|fs.ModeSticky) != 0 || info.Mode().Perm()&0077 != 0 {
+		return false
+	}
+	uid, _, ok := fileOwner(info)
+	return ok && uid == os.Geteuid()
+}
+
+// isOrphanedScript reports whether a PID-stamped script belongs to a process
+// that no longer exists (e.g. the shell was killed or the terminal crashed)
+func isOrphanedScript(name string) bool {
+	pid, ok := scriptOwnerPID(name)
+	if !ok {
+		return false
+	}
+	return !processAlive(pid)
+}
+
 // CleanupOldScripts is a public function to clean up old autocd scripts
 // Applications can call this periodically to prevent temp directory buildup
 func CleanupOldScripts() error {
@@ -91,15 +266,15 @@ func DirectoryExists(path string) bool {
 	return info.IsDir()
 }
 
-// IsDirectoryAccessible checks if directory can be read and accessed
+// IsDirectoryAccessible checks if directory can be entered (cd semantics).
+// Only search (execute) permission is required, so execute-only
+// directories are accessible even though they cannot be listed.
 func IsDirectoryAccessible(path string) bool {
 	if !DirectoryExists(path) {
 		return false
 	}
 
-	// Try to read the directory
-	_, err := os.ReadDir(path)
-	return err == nil
+	return checkAccess(path, accessExecute) == nil
 }
 
 // GetTempDir returns the system temp directory or a custom one if specified
@@ -107,7 +282,7 @@ func GetTempDir(customTempDir string) string {
 	if customTempDir != "" && DirectoryExists(customTempDir) {
 		return customTempDir
 	}
-	return os.TempDir()
+	return systemTempDir()
 }
 
 // SetExecutablePermissions sets executable permissions on Unix systems
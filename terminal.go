@@ -0,0 +1,227 @@
+package autocd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// renderTerminalSequences builds the script lines that emit terminal escape
+// sequences for the transition. Output is skipped when stdout is not a TTY.
+func renderTerminalSequences(targetDir string, opts *Options) string {
+	var b strings.Builder
+
+	if opts.TerminalTitle != "" {
+		title := expandTitleTemplate(opts.TerminalTitle, targetDir)
+		b.WriteString(`[ -t 1 ] && printf '\033]0;%s\007' '`)
+		b.WriteString(sanitizePathForShell(title))
+		b.WriteString("'\n")
+	}
+
+	// Emacs terminal buffers track the cwd through their own escapes
+	switch emacsTerminal() {
+	case "vterm":
+		b.WriteString(`[ -t 1 ] && printf '\033]51;A%s\033\\' '`)
+		b.WriteString(sanitizePathForShell(userAtHost() + ":" + targetDir))
+		b.WriteString("'\n")
+	case "term":
+		b.WriteString(`[ -t 1 ] && printf '\033AnSiTc %s\n' '`)
+		b.WriteString(sanitizePathForShell(targetDir))
+		b.WriteString("'\n")
+	}
+
+	// IDE terminals learn the cwd for new tabs, links and split panes
+	switch ideTerminal() {
+	case "vscode":
+		b.WriteString(`[ -t 1 ] && printf '\033]633;P;Cwd=%s\007' '`)
+		b.WriteString(sanitizePathForShell(vscodeEscape(targetDir)))
+		b.WriteString("'\n")
+	case "jetbrains":
+		if !opts.ReportDirectory {
+			b.WriteString(`[ -t 1 ] && printf '\033]7;%s\007' '`)
+			b.WriteString(sanitizePathForShell(fileURL(targetDir)))
+			b.WriteString("'\n")
+		}
+	}
+
+	// OSC 7 for WezTerm, iTerm2, kitty, foot, ...; OSC 9;9 for Windows Terminal
+	if opts.ReportDirectory {
+		b.WriteString(`[ -t 1 ] && printf '\033]7;%s\007' '`)
+		b.WriteString(sanitizePathForShell(fileURL(targetDir)))
+		b.WriteString("'\n")
+		b.WriteString(`[ -t 1 ] && printf '\033]9;9;%s\007' '`)
+		b.WriteString(sanitizePathForShell(invalidCharsRegex.ReplaceAllString(targetDir, "")))
+		b.WriteString("'\n")
+	}
+
+	return b.String()
+}
+
+// ideTerminal identifies the IDE integrated terminal hosting the process:
+// "vscode", "jetbrains", or "" when not inside one
+func ideTerminal() string {
+	switch {
+	case os.Getenv("TERM_PROGRAM") == "vscode":
+		return "vscode"
+	case strings.HasPrefix(os.Getenv("TERMINAL_EMULATOR"), "JetBrains"):
+		return "jetbrains"
+	default:
+		return ""
+	}
+}
+
+// vscodeEscape encodes a value for VS Code's OSC 633 shell integration
+// sequences, which reserve backslash and semicolon and end at control
+// characters
+func vscodeEscape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == ';' || c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// renderSemanticMarks returns the script lines wrapping the transition
+// output in OSC 133 marks: the banner becomes command output, finished with
+// the application's exit status, so the inherited shell's prompt starts a
+// new zone for terminals with prompt navigation
+func renderSemanticMarks(opts *Options) (start, finish string) {
+	if !escapesSupported(opts) {
+		return "", ""
+	}
+	start = `[ -t 1 ] && printf '\033]133;C\007'` + "\n"
+	finish = fmt.Sprintf(`[ -t 1 ] && printf '\033]133;D;%d\007'`+"\n", opts.AppExitStatus)
+	return start, finish
+}
+
+// emacsTerminal identifies the Emacs terminal emulator hosting the process:
+// "vterm", "term" (term/ansi-term), or "" when not inside Emacs
+func emacsTerminal() string {
+	if os.Getenv("EMACS_VTERM_PATH") != "" {
+		return "vterm"
+	}
+
+	insideEmacs := os.Getenv("INSIDE_EMACS")
+	switch {
+	case insideEmacs == "":
+		return ""
+	case strings.Contains(insideEmacs, "vterm"):
+		return "vterm"
+	case strings.Contains(insideEmacs, "term"):
+		return "term"
+	default:
+		return ""
+	}
+}
+
+// userAtHost returns "user@host" as expected by vterm directory tracking
+func userAtHost() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+}
+
+// expandTitleTemplate substitutes {dir} and {base} in a title template and
+// strips control characters that would terminate the escape sequence early
+func expandTitleTemplate(template, targetDir string) string {
+	title := strings.NewReplacer(
+		"{dir}", targetDir,
+		"{base}", filepath.Base(targetDir),
+	).Replace(template)
+	return invalidCharsRegex.ReplaceAllString(title, "")
+}
+
+// osc52Sequence returns the OSC 52 escape that sets the clipboard to text.
+// Inside tmux the sequence is wrapped in a passthrough DCS.
+func osc52Sequence(text string) string {
+	seq := "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if os.Getenv("TMUX") != "" {
+		return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	}
+	return seq
+}
+
+// copyToClipboard asks the terminal to copy text via OSC 52. It writes to
+// the controlling terminal, so it works over SSH and with redirected stdout.
+func copyToClipboard(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(osc52Sequence(text))
+	return err
+}
+
+// escapeTerminal reports whether the hosting terminal is known to handle
+// OSC 8 links and OSC 133 marks, judged by the variables terminals set
+// for themselves. TERM alone says too little: many terminals claim
+// xterm-256color.
+func escapeTerminal() bool {
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Tabby", "rio", "WarpTerminal":
+		return true
+	}
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("WT_SESSION") != "" {
+		return true
+	}
+	if version, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && version >= 5000 {
+		return true
+	}
+	if version, err := strconv.Atoi(os.Getenv("KONSOLE_VERSION")); err == nil && version >= 201200 {
+		return true
+	}
+	switch term := os.Getenv("TERM"); {
+	case term == "xterm-kitty", term == "xterm-ghostty", term == "wezterm", strings.HasPrefix(term, "foot"):
+		return true
+	}
+	return false
+}
+
+// escapesSupported reports whether optional escapes (links, semantic
+// marks) may be written: not disabled by PlainOutput, and only on
+// terminals known to handle them, since others print the escape. Whether
+// the stream is a terminal is checked on output.
+func escapesSupported(opts *Options) bool {
+	if opts.PlainOutput {
+		return false
+	}
+	switch os.Getenv("TERM") {
+	case "", "dumb", "linux":
+		return false
+	}
+	return emacsTerminal() == "" && escapeTerminal()
+}
+
+// fileURL returns the file:// URL of a local path, including the host name
+// so terminals can tell local paths from remote ones
+func fileURL(path string) string {
+	host, _ := os.Hostname()
+	return (&url.URL{Scheme: "file", Host: host, Path: path}).String()
+}
+
+// displayPath renders path for a message written to fd, as an OSC 8
+// hyperlink when fd is a terminal that supports it
+func displayPath(path string, fd int, opts *Options) string {
+	if !escapesSupported(opts) || !isTerminal(fd) {
+		return path
+	}
+	clean := invalidCharsRegex.ReplaceAllString(path, "")
+	return "\033]8;;" + fileURL(clean) + "\033\\" + clean + "\033]8;;\033\\"
+}
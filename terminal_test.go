@@ -0,0 +1,284 @@
+package autocd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// Test terminal title template expansion
+func TestExpandTitleTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		dir      string
+		expected string
+	}{
+		{"full_dir", "{dir}", "/home/user/project", "/home/user/project"},
+		{"base_name", "myapp: {base}", "/home/user/project", "myapp: project"},
+		{"control_chars", "{dir}", "/tmp/evil\x07\x1b]0;x", "/tmp/evil]0;x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expandTitleTemplate(tt.template, tt.dir)
+			if got != tt.expected {
+				t.Errorf("expandTitleTemplate(%q, %q) = %q, want %q", tt.template, tt.dir, got, tt.expected)
+			}
+		})
+	}
+}
+
+// Test that the title sequence is only emitted when configured
+func TestGenerateScript_TerminalTitle(t *testing.T) {
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+
+	script, err := generateScript("/tmp/test", shell, &Options{}, nil)
+	if err != nil {
+		t.Fatalf("Script generation failed: %v", err)
+	}
+	if strings.Contains(script, `\033]0;`) {
+		t.Error("Script should not set the terminal title by default")
+	}
+
+	script, err = generateScript("/tmp/it's", shell, &Options{TerminalTitle: "{base}"}, nil)
+	if err != nil {
+		t.Fatalf("Script generation failed: %v", err)
+	}
+	if !strings.Contains(script, `[ -t 1 ] && printf '\033]0;%s\007' 'it'"'"'s'`) {
+		t.Errorf("Script should set the escaped terminal title, got:\n%s", script)
+	}
+}
+
+// Test Emacs terminal detection and directory-tracking escapes
+func TestRenderTerminalSequences_Emacs(t *testing.T) {
+	original, had := os.LookupEnv("INSIDE_EMACS")
+	originalVterm, hadVterm := os.LookupEnv("EMACS_VTERM_PATH")
+	defer func() {
+		restoreEnv("INSIDE_EMACS", original, had)
+		restoreEnv("EMACS_VTERM_PATH", originalVterm, hadVterm)
+	}()
+	os.Unsetenv("EMACS_VTERM_PATH")
+
+	tests := []struct {
+		insideEmacs string
+		expected    string
+	}{
+		{"", ""},
+		{"29.1,vterm", `\033]51;A`},
+		{"29.1,term:0.96", `\033AnSiTc %s\n`},
+		{"29.1,eshell", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.insideEmacs, func(t *testing.T) {
+			os.Setenv("INSIDE_EMACS", tt.insideEmacs)
+			output := renderTerminalSequences("/tmp/project", &Options{})
+
+			if tt.expected == "" && output != "" {
+				t.Errorf("Expected no escapes, got %q", output)
+			}
+			if tt.expected != "" && (!strings.Contains(output, tt.expected) || !strings.Contains(output, "/tmp/project")) {
+				t.Errorf("Expected %q with the target directory, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+// Test cwd escapes and defaults for IDE integrated terminals
+func TestIDETerminal(t *testing.T) {
+	originalProgram, hadProgram := os.LookupEnv("TERM_PROGRAM")
+	originalEmulator, hadEmulator := os.LookupEnv("TERMINAL_EMULATOR")
+	originalEmacs, hadEmacs := os.LookupEnv("INSIDE_EMACS")
+	originalVterm, hadVterm := os.LookupEnv("EMACS_VTERM_PATH")
+	defer func() {
+		restoreEnv("TERM_PROGRAM", originalProgram, hadProgram)
+		restoreEnv("TERMINAL_EMULATOR", originalEmulator, hadEmulator)
+		restoreEnv("INSIDE_EMACS", originalEmacs, hadEmacs)
+		restoreEnv("EMACS_VTERM_PATH", originalVterm, hadVterm)
+	}()
+	for _, name := range []string{"TERM_PROGRAM", "TERMINAL_EMULATOR", "INSIDE_EMACS", "EMACS_VTERM_PATH"} {
+		os.Unsetenv(name)
+	}
+
+	if ideTerminal() != "" || defaultDepthWarningThreshold() != 15 {
+		t.Error("Expected no IDE terminal and the standard depth threshold")
+	}
+
+	os.Setenv("TERM_PROGRAM", "vscode")
+	output := renderTerminalSequences("/tmp/a;b", &Options{})
+	if !strings.Contains(output, `\033]633;P;Cwd=%s`) || !strings.Contains(output, `/tmp/a\x3bb`) {
+		t.Errorf("Expected a VS Code cwd sequence, got %q", output)
+	}
+	if defaultDepthWarningThreshold() != 20 {
+		t.Errorf("Expected a raised depth threshold in VS Code, got %d", defaultDepthWarningThreshold())
+	}
+	if SupportReport().IDETerminal != "vscode" {
+		t.Error("Expected the IDE terminal in the support report")
+	}
+
+	os.Unsetenv("TERM_PROGRAM")
+	os.Setenv("TERMINAL_EMULATOR", "JetBrains-JediTerm")
+	output = renderTerminalSequences("/tmp/project", &Options{})
+	if !strings.Contains(output, `\033]7;%s`) || !strings.Contains(output, "/tmp/project") {
+		t.Errorf("Expected an OSC 7 cwd sequence for JetBrains, got %q", output)
+	}
+
+	if got := vscodeEscape(`/a\b;c` + "\n"); got != `/a\\b\x3bc\x0a` {
+		t.Errorf("Unexpected VS Code escaping: %q", got)
+	}
+}
+
+// Test the OSC 133 marks around the transition output
+func TestGenerateScript_SemanticMarks(t *testing.T) {
+	originalTerm, had := os.LookupEnv("TERM")
+	defer restoreEnv("TERM", originalTerm, had)
+	os.Setenv("TERM", "xterm-kitty")
+
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+	script, _ := generateScript("/tmp/project", shell, &Options{AppExitStatus: 3}, nil)
+	start := strings.Index(script, `\033]133;C\007`)
+	banner := strings.Index(script, "Directory changed to")
+	finish := strings.Index(script, `\033]133;D;3\007`)
+	exec := strings.LastIndex(script, "exec ")
+	if start < 0 || !(start < banner && banner < finish && finish < exec) {
+		t.Errorf("Expected C before the banner and D;3 before exec:\n%s", script)
+	}
+
+	plain, _ := generateScript("/tmp/project", shell, &Options{PlainOutput: true}, nil)
+	if strings.Contains(plain, "133;") {
+		t.Errorf("PlainOutput should disable semantic marks:\n%s", plain)
+	}
+
+	clearTerminalIdentity(t)
+	os.Setenv("TERM", "xterm-256color")
+	unknown, _ := generateScript("/tmp/project", shell, &Options{}, nil)
+	if strings.Contains(unknown, "133;") {
+		t.Errorf("Unidentified terminals should not get semantic marks:\n%s", unknown)
+	}
+}
+
+// clearTerminalIdentity unsets the variables escapeTerminal recognizes
+// terminals by, for the duration of a test
+func clearTerminalIdentity(t *testing.T) {
+	for _, name := range []string{"TERM_PROGRAM", "KITTY_WINDOW_ID", "WT_SESSION", "VTE_VERSION", "KONSOLE_VERSION"} {
+		t.Setenv(name, "")
+	}
+}
+
+// restoreEnv resets an environment variable to its saved state
+func restoreEnv(name, value string, had bool) {
+	if had {
+		os.Setenv(name, value)
+	} else {
+		os.Unsetenv(name)
+	}
+}
+
+// Test the OSC 52 clipboard sequence, plain and wrapped for tmux
+func TestOSC52Sequence(t *testing.T) {
+	originalTmux, had := os.LookupEnv("TMUX")
+	defer restoreEnv("TMUX", originalTmux, had)
+
+	os.Unsetenv("TMUX")
+	if seq := osc52Sequence("/tmp/x"); seq != "\033]52;c;L3RtcC94\a" {
+		t.Errorf("Unexpected OSC 52 sequence %q", seq)
+	}
+
+	os.Setenv("TMUX", "/tmp/tmux-0/default,1,0")
+	if seq := osc52Sequence("/tmp/x"); seq != "\033Ptmux;\033\033]52;c;L3RtcC94\a\033\\" {
+		t.Errorf("Unexpected tmux-wrapped sequence %q", seq)
+	}
+}
+
+// Test OSC 8 hyperlinks in the transition script messages
+func TestGenerateScript_Hyperlinks(t *testing.T) {
+	originalTerm, had := os.LookupEnv("TERM")
+	defer restoreEnv("TERM", originalTerm, had)
+	os.Setenv("TERM", "xterm-256color")
+	t.Setenv("INSIDE_EMACS", "")
+	t.Setenv("EMACS_VTERM_PATH", "")
+	clearTerminalIdentity(t)
+
+	shell := &ShellInfo{Path: "/bin/sh", IsValid: true}
+	if unknown, _ := generateScript("/tmp/my dir", shell, &Options{}, nil); strings.Contains(unknown, `\033]8;;`) {
+		t.Errorf("Terminals not known to render links should get plain output:\n%s", unknown)
+	}
+
+	t.Setenv("TERM_PROGRAM", "WezTerm")
+	script, _ := generateScript("/tmp/my dir", shell, &Options{}, nil)
+	if !strings.Contains(script, "TARGET_URL='file://") || !strings.Contains(script, "/tmp/my%20dir'") {
+		t.Errorf("Expected an escaped file:// URL in the script:\n%s", script)
+	}
+	if !strings.Contains(script, `if [ -t 1 ]; then`) || !strings.Contains(script, `\033]8;;`) {
+		t.Errorf("Expected a terminal-gated OSC 8 banner:\n%s", script)
+	}
+
+	plain, _ := generateScript("/tmp/my dir", shell, &Options{PlainOutput: true}, nil)
+	if strings.Contains(plain, "TARGET_URL") || strings.Contains(plain, `\033]8;;`) {
+		t.Errorf("PlainOutput should disable hyperlinks:\n%s", plain)
+	}
+
+	os.Setenv("TERM", "dumb")
+	if escapesSupported(&Options{}) {
+		t.Error("Dumb terminals should not get hyperlinks")
+	}
+}
+
+// Test that the hyperlinked banner still prints plainly when not on a TTY
+func TestTransitionScript_HyperlinkFallback(t *testing.T) {
+	originalTerm, had := os.LookupEnv("TERM")
+	defer restoreEnv("TERM", originalTerm, had)
+	os.Setenv("TERM", "xterm-256color")
+	t.Setenv("TERM_PROGRAM", "WezTerm")
+
+	dir := t.TempDir()
+	output := runTransitionScript(t, dir, &Options{})
+	if !strings.Contains(output, "Directory changed to: "+dir+"\n") {
+		t.Errorf("Expected a plain banner without a terminal, got:\n%s", output)
+	}
+}
+
+// Test the OSC 7 and OSC 9;9 cwd reports
+func TestRenderTerminalSequences_ReportDirectory(t *testing.T) {
+	originalEmulator, hadEmulator := os.LookupEnv("TERMINAL_EMULATOR")
+	defer restoreEnv("TERMINAL_EMULATOR", originalEmulator, hadEmulator)
+	os.Setenv("TERMINAL_EMULATOR", "JetBrains-JediTerm")
+
+	output := renderTerminalSequences("/tmp/my project", &Options{ReportDirectory: true})
+	if strings.Count(output, `\033]7;%s`) != 1 || !strings.Contains(output, fileURL("/tmp/my project")) {
+		t.Errorf("Expected exactly one OSC 7 report, got %q", output)
+	}
+	if !strings.Contains(output, `\033]9;9;%s\007' '/tmp/my project'`) {
+		t.Errorf("Expected an OSC 9;9 report, got %q", output)
+	}
+}
+
+// Test the known-terminal check gating optional escapes
+func TestEscapeTerminal(t *testing.T) {
+	clearTerminalIdentity(t)
+	t.Setenv("TERM", "xterm-256color")
+	if escapeTerminal() {
+		t.Error("xterm-256color alone should not count as a known terminal")
+	}
+
+	for name, value := range map[string]string{
+		"TERM_PROGRAM":    "iTerm.app",
+		"KITTY_WINDOW_ID": "1",
+		"VTE_VERSION":     "7200",
+		"KONSOLE_VERSION": "230402",
+		"TERM":            "foot-extra",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if !escapeTerminal() {
+				t.Errorf("%s=%s should count as a known terminal", name, value)
+			}
+		})
+	}
+
+	t.Setenv("VTE_VERSION", "4205")
+	if escapeTerminal() {
+		t.Error("Old VTE versions do not render links")
+	}
+}
@@ -0,0 +1,71 @@
+package autocd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PhaseTiming records how long one phase of a transition took
+type PhaseTiming struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// phaseTimer measures consecutive phases of ExitWithDirectoryAdvanced
+type phaseTimer struct {
+	start  time.Time
+	last   time.Time
+	phases []PhaseTiming
+	budget time.Duration // Limit for all phases (0 = unlimited)
+}
+
+func newPhaseTimer(budget time.Duration) *phaseTimer {
+	now := time.Now()
+	return &phaseTimer{start: now, last: now, budget: budget}
+}
+
+// mark ends the current phase, attributing the time since the previous mark to it
+func (t *phaseTimer) mark(phase string) {
+	now := time.Now()
+	t.phases = append(t.phases, PhaseTiming{Phase: phase, Duration: now.Sub(t.last)})
+	t.last = now
+}
+
+// overBudget returns ErrPrepareTimeout, naming the last phase, once the
+// marked phases exceed the budget
+func (t *phaseTimer) overBudget() error {
+	if t.budget <= 0 || len(t.phases) == 0 {
+		return nil
+	}
+	if elapsed := t.last.Sub(t.start); elapsed > t.budget {
+		return fmt.Errorf("%w: %s spent by the end of %s (budget %s)",
+			ErrPrepareTimeout, elapsed.Round(time.Microsecond), t.phases[len(t.phases)-1].Phase, t.budget)
+	}
+	return nil
+}
+
+// capped limits a blocking wait to the budget left, if any
+func (t *phaseTimer) capped(wait time.Duration) time.Duration {
+	if t.budget <= 0 {
+		return wait
+	}
+	left := t.budget - time.Since(t.start)
+	switch {
+	case left <= 0:
+		return 0
+	case left < wait:
+		return left
+	default:
+		return wait
+	}
+}
+
+// String renders the phases for debug output, e.g. "cleanup=1.2ms validation=40µs"
+func (t *phaseTimer) String() string {
+	parts := make([]string, len(t.phases))
+	for i, p := range t.phases {
+		parts[i] = fmt.Sprintf("%s=%s", p.Phase, p.Duration)
+	}
+	return strings.Join(parts, " ")
+}
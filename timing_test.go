@@ -0,0 +1,99 @@
+package autocd
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that phases are recorded in order with non-negative durations
+func TestPhaseTimer(t *testing.T) {
+	timer := newPhaseTimer(0)
+	timer.mark("cleanup")
+	time.Sleep(2 * time.Millisecond)
+	timer.mark("validation")
+
+	if len(timer.phases) != 2 {
+		t.Fatalf("Expected 2 phases, got %d", len(timer.phases))
+	}
+	if timer.phases[0].Phase != "cleanup" || timer.phases[1].Phase != "validation" {
+		t.Errorf("Unexpected phase order: %+v", timer.phases)
+	}
+	if timer.phases[0].Duration < 0 || timer.phases[1].Duration < 2*time.Millisecond {
+		t.Errorf("Unexpected durations: %+v", timer.phases)
+	}
+
+	rendered := timer.String()
+	if !strings.HasPrefix(rendered, "cleanup=") || !strings.Contains(rendered, " validation=") {
+		t.Errorf("Unexpected rendering: %q", rendered)
+	}
+}
+
+// Test that phase timings appear in the JSON transition result
+func TestTransitionResult_Phases(t *testing.T) {
+	result := TransitionResult{
+		TargetDir: "/tmp",
+		Phases:    []PhaseTiming{{Phase: "cleanup", Duration: 1500 * time.Microsecond}},
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"phases":[{"phase":"cleanup","duration_ns":1500000}]`) {
+		t.Errorf("Phases missing from JSON: %s", data)
+	}
+
+	data, _ = json.Marshal(TransitionResult{TargetDir: "/tmp"})
+	if strings.Contains(string(data), "phases") {
+		t.Errorf("Expected empty phases to be omitted: %s", data)
+	}
+}
+
+// Test the preparation budget and the waits capped by it
+func TestPhaseTimer_Budget(t *testing.T) {
+	timer := newPhaseTimer(5 * time.Millisecond)
+	timer.mark("cleanup")
+	if err := timer.overBudget(); err != nil {
+		t.Errorf("Expected no timeout yet, got: %v", err)
+	}
+	if wait := timer.capped(time.Second); wait > 5*time.Millisecond {
+		t.Errorf("Expected the wait capped by the budget, got %s", wait)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	timer.mark("validation")
+	err := timer.overBudget()
+	if !errors.Is(err, ErrPrepareTimeout) || !strings.Contains(err.Error(), "end of validation") {
+		t.Errorf("Expected a timeout naming the validation phase, got: %v", err)
+	}
+	if wait := timer.capped(time.Second); wait != 0 {
+		t.Errorf("Expected no wait once the budget is spent, got %s", wait)
+	}
+
+	if newPhaseTimer(0).capped(time.Second) != time.Second {
+		t.Error("Waits should be unchanged without a budget")
+	}
+}
+
+// Test that an exhausted budget aborts the transition with a recoverable error
+func TestExitWithDirectoryAdvanced_PrepareTimeout(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "clone")
+	err := ExitWithDirectoryAdvanced(missing, &Options{
+		WaitForTarget:        time.Second,
+		PrepareTimeout:       20 * time.Millisecond,
+		DisableCleanup:       true,
+		DisableDepthWarnings: true,
+	})
+
+	var autocdErr *AutoCDError
+	if !errors.As(err, &autocdErr) || autocdErr.Type != ErrorTimeout || !autocdErr.IsRecoverable() {
+		t.Fatalf("Expected a recoverable timeout error, got: %v", err)
+	}
+	if !errors.Is(err, ErrPrepareTimeout) {
+		t.Errorf("Expected ErrPrepareTimeout, got: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package autocd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// TmuxDirEnv is the tmux session variable set by Options.TmuxExport
+const TmuxDirEnv = "AUTOCD_DIR"
+
+// tmuxProgram is the tmux client used to update the session (replaceable
+// in tests)
+var tmuxProgram = "tmux"
+
+// tmuxTimeout bounds each tmux call so a wedged server cannot stall exec
+const tmuxTimeout = time.Second
+
+// insideTmux reports whether the process runs in a tmux pane
+func insideTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// exportToTmux records the target directory in the tmux session environment
+// and/or the pane title, so panes and windows created later can see it.
+// Outside tmux it does nothing.
+func exportToTmux(targetDir string, opts *Options) error {
+	if !insideTmux() {
+		return nil
+	}
+
+	if opts.TmuxExport {
+		if err := runTmux("set-environment", TmuxDirEnv, targetDir); err != nil {
+			return err
+		}
+	}
+
+	if opts.TmuxPaneTitle != "" {
+		args := []string{"select-pane"}
+		if pane := os.Getenv("TMUX_PANE"); pane != "" {
+			args = append(args, "-t", pane)
+		}
+		args = append(args, "-T", expandTitleTemplate(opts.TmuxPaneTitle, targetDir))
+		if err := runTmux(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTmux runs one tmux command against the current server
+func runTmux(args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), tmuxTimeout)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, tmuxProgram, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("tmux %s: %w: %s", args[0], err, output)
+	}
+	return nil
+}
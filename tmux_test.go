@@ -0,0 +1,53 @@
+package autocd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test the tmux session variable and pane title updates
+func TestExportToTmux(t *testing.T) {
+	dir := t.TempDir()
+	record := filepath.Join(dir, "calls")
+	fake := filepath.Join(dir, "tmux")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$*\" >> '" + record + "'\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake tmux: %v", err)
+	}
+
+	original := tmuxProgram
+	defer func() { tmuxProgram = original }()
+	tmuxProgram = fake
+
+	originalTmux, hadTmux := os.LookupEnv("TMUX")
+	defer restoreEnv("TMUX", originalTmux, hadTmux)
+	originalPane, hadPane := os.LookupEnv("TMUX_PANE")
+	defer restoreEnv("TMUX_PANE", originalPane, hadPane)
+
+	opts := &Options{TmuxExport: true, TmuxPaneTitle: "app: {base}"}
+
+	os.Unsetenv("TMUX")
+	if err := exportToTmux("/srv/project", opts); err != nil {
+		t.Fatalf("exportToTmux failed outside tmux: %v", err)
+	}
+	if _, err := os.Stat(record); err == nil {
+		t.Error("tmux should not be called outside a tmux session")
+	}
+
+	os.Setenv("TMUX", "/tmp/tmux-0/default,1,0")
+	os.Setenv("TMUX_PANE", "%3")
+	if err := exportToTmux("/srv/project", opts); err != nil {
+		t.Fatalf("exportToTmux failed: %v", err)
+	}
+	got, _ := os.ReadFile(record)
+	expected := "set-environment AUTOCD_DIR /srv/project\nselect-pane -t %3 -T app: project\n"
+	if string(got) != expected {
+		t.Errorf("Unexpected tmux calls:\n%s\nwant:\n%s", got, expected)
+	}
+
+	tmuxProgram = "/bin/false"
+	if err := exportToTmux("/srv/project", opts); err == nil {
+		t.Error("Expected a failing tmux call to be reported")
+	}
+}
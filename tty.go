@@ -0,0 +1,43 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+)
+
+// standardStreams names stdin, stdout and stderr for error messages
+var standardStreams = []string{"stdin", "stdout", "stderr"}
+
+// checkTerminal verifies the process can host an interactive shell: it
+// needs a controlling terminal and a terminal on stdin. stdout and stderr
+// are only checked with requireOutput, so "app 2>debug.log" or "app | tee"
+// keep working. With reopen set, non-terminal streams that are checked are
+// replaced by /dev/tty instead. Where the platform cannot tell terminals
+// apart (terminalCheckable is false), the streams are not checked.
+func checkTerminal(reopen, requireOutput bool) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("%w: no controlling terminal: %v", ErrNoTerminal, err)
+	}
+	defer tty.Close()
+	if !terminalCheckable {
+		return nil
+	}
+
+	streams := standardStreams[:1]
+	if requireOutput {
+		streams = standardStreams
+	}
+	for fd, name := range streams {
+		if isTerminal(fd) {
+			continue
+		}
+		if !reopen {
+			return fmt.Errorf("%w: %s is not a terminal", ErrNoTerminal, name)
+		}
+		if err := dupFD(int(tty.Fd()), fd); err != nil {
+			return fmt.Errorf("%w: cannot reopen %s on /dev/tty: %v", ErrNoTerminal, name, err)
+		}
+	}
+	return nil
+}
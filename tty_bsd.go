@@ -0,0 +1,34 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package autocd
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// terminalCheckable reports that isTerminal gives real answers here
+const terminalCheckable = true
+
+// isTerminal reports whether fd refers to a terminal (TIOCGETA succeeds)
+func isTerminal(fd int) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCGETA, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// dupFD makes newfd a copy of oldfd
+func dupFD(oldfd, newfd int) error {
+	return syscall.Dup2(oldfd, newfd)
+}
+
+// setForegroundGroup makes pgrp the foreground process group of the
+// terminal fd (tcsetpgrp)
+func setForegroundGroup(fd, pgrp int) error {
+	pgid := int32(pgrp)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&pgid)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package autocd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// Test that redirected output passes the terminal check unless terminal
+// output is required. The check runs in a child process on a pty from
+// script(1), with stdout sent to a pipe.
+func TestCheckTerminal_RedirectedOutput(t *testing.T) {
+	if os.Getenv("AUTOCD_TEST_REDIRECTED") == "1" {
+		fmt.Fprintln(os.Stderr, "default:", checkTerminal(false, false))
+		fmt.Fprintln(os.Stderr, "required:", checkTerminal(false, true))
+		return
+	}
+
+	script, err := exec.LookPath("script")
+	if err != nil {
+		t.Skip("script not available")
+	}
+	command := shellQuote(os.Args[0]) + " -test.run='^TestCheckTerminal_RedirectedOutput$' | cat >/dev/null"
+	cmd := exec.Command(script, "-qec", command, "/dev/null")
+	cmd.Env = append(os.Environ(), "AUTOCD_TEST_REDIRECTED=1")
+	output, _ := cmd.CombinedOutput()
+	if !strings.Contains(string(output), "default: <nil>") {
+		t.Errorf("Redirected stdout should pass by default, got:\n%s", output)
+	}
+	if !strings.Contains(string(output), "required: "+ErrNoTerminal.Error()+": stdout is not a terminal") {
+		t.Errorf("Expected stdout to fail when terminal output is required, got:\n%s", output)
+	}
+}
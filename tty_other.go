@@ -0,0 +1,28 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package autocd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// terminalCheckable is false: whether a stream is a terminal is unknown
+// here, so checkTerminal skips the stream checks
+const terminalCheckable = false
+
+// isTerminal cannot query terminal attributes on this platform and reports
+// false; callers needing a real answer check terminalCheckable first
+func isTerminal(fd int) bool {
+	return false
+}
+
+// dupFD is not implemented on this platform
+func dupFD(oldfd, newfd int) error {
+	return fmt.Errorf("dup2 is not supported on %s", runtime.GOOS)
+}
+
+// setForegroundGroup is not implemented on this platform
+func setForegroundGroup(fd, pgrp int) error {
+	return fmt.Errorf("tcsetpgrp is not supported on %s", runtime.GOOS)
+}
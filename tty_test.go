@@ -0,0 +1,46 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// Test terminal detection on non-terminal descriptors
+func TestIsTerminal_NonTerminals(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if isTerminal(int(r.Fd())) {
+		t.Error("A pipe should not be reported as a terminal")
+	}
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	if isTerminal(int(devNull.Fd())) {
+		t.Error("/dev/null should not be reported as a terminal")
+	}
+}
+
+// Test that a missing terminal yields ErrNoTerminal
+func TestCheckTerminal_NoTerminal(t *testing.T) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		tty.Close()
+		if isTerminal(0) {
+			t.Skip("running inside a terminal")
+		}
+	}
+
+	err := checkTerminal(false, false)
+	if !errors.Is(err, ErrNoTerminal) {
+		t.Errorf("Expected ErrNoTerminal, got: %v", err)
+	}
+}
@@ -1,5 +1,10 @@
 package autocd
 
+import (
+	"io"
+	"time"
+)
+
 // SecurityLevel defines path validation strictness
 type SecurityLevel int
 
@@ -11,18 +16,147 @@ const (
 
 // ShellInfo contains detected shell information
 type ShellInfo struct {
-	Path    string // Full path to shell executable
-	IsValid bool   // Whether shell exists and is executable
+	Path    string   // Full path to shell executable
+	IsValid bool     // Whether shell exists and is executable
+	Args    []string // Extra arguments passed to the shell (from a command-line override)
+}
+
+// DetectionSource identifies one tier of shell detection
+type DetectionSource int
+
+const (
+	DetectOverride      DetectionSource = iota // Options.Shell, when set
+	DetectParentProcess                        // The shell that launched this process
+	DetectShellEnv                             // The SHELL environment variable
+	DetectPasswd                               // The user's login shell from passwd
+	DetectFallback                             // /bin/sh
+)
+
+// MissingTargetPolicy decides what happens when the target directory has
+// disappeared by the time it is revalidated before exec
+type MissingTargetPolicy int
+
+const (
+	MissingTargetAbort       MissingTargetPolicy = iota // Default: return the validation error
+	MissingTargetAncestor                               // Use the nearest existing parent directory
+	MissingTargetFallbackDir                            // Use Options.FallbackDir
+)
+
+// DepthAction decides what happens when the shell depth reaches
+// Options.DepthLimit
+type DepthAction int
+
+const (
+	DepthActionWarn     DepthAction = iota // Default: only the depth tip
+	DepthActionBlock                       // Return ErrDepthLimit instead of nesting another shell
+	DepthActionCollapse                    // Write the target to DepthStateFile() and exit for an outer wrapper to cd
+)
+
+// Strategy is one way of taking the user to the target directory, tried in
+// order by Options.Strategies
+type Strategy int
+
+const (
+	StrategyExec         Strategy = iota // Replace the process with the transition script (the default)
+	StrategySpawn                        // Run the shell as a child process and exit with its status
+	StrategyTmux                         // Inside tmux, open a new window in the target and exit
+	StrategyPrintPath                    // Print the directory on stdout and exit
+	StrategySentinelFile                 // Write the directory to Options.SentinelFile and exit
+)
+
+// ShellDefinition holds the body of a function or alias for each rc
+// dialect. Dialects left empty are skipped; Zsh falls back to POSIX.
+type ShellDefinition struct {
+	POSIX string // sh, bash, dash, ksh, ... (and zsh unless Zsh is set)
+	Zsh   string // zsh-specific variant
+	Fish  string // fish syntax
 }
 
 // Options provides configuration for ExitWithDirectoryAdvanced
 type Options struct {
-	Shell                 string        // Override shell detection ("", "bash", "zsh", etc.)
-	SecurityLevel         SecurityLevel // Strict, Normal, Permissive
-	DebugMode             bool          // Enable verbose logging to stderr
-	TempDir               string        // Override temp directory ("" = system default)
-	DepthWarningThreshold int           // Shell depth threshold for warnings (default: 15)
-	DisableDepthWarnings  bool          // Disable shell depth warning messages (default: false)
+	Shell                 string                     // Override shell detection ("", "bash", "zsh", "bash --noprofile -i", etc.)
+	LoginShell            bool                       // Start the shell as a login shell (-l) so profile files are loaded; bash with rc customizations loads them from the injected rc
+	ShellArgs             []string                   // Extra arguments for the shell, e.g. []string{"-i"}, after -l and before Shell's own
+	SecurityLevel         SecurityLevel              // Strict, Normal, Permissive
+	DebugMode             bool                       // Enable verbose logging to stderr
+	DryRun                bool                       // Print the transition script to stdout and return nil instead of exec'ing it
+	AppName               string                     // Application name in script names, messages, audit records and AUTOCD_APP ("" = executable name)
+	TempDir               string                     // Override temp directory ("" = system default)
+	CleanupMaxAge         time.Duration              // Age after which the per-call sweep removes old scripts (default: 1h)
+	DisableCleanup        bool                       // Skip the per-call sweep of old scripts (e.g. when a cron job cleans up)
+	DepthWarningThreshold int                        // Shell depth threshold for warnings (default: 15, 20 in IDE terminals)
+	DisableDepthWarnings  bool                       // Disable shell depth warning messages (default: false)
+	DepthWarningInterval  int                        // Show the depth tip at most once every N transitions of a terminal session (0 = every time)
+	ResetShellDepth       bool                       // Export SHLVL so the spawned shell reports depth 1 (combine with CleanEnvironment for a minimal environment)
+	DepthAction           DepthAction                // What to do at DepthLimit: warn, block or collapse (default: warn)
+	DepthLimit            int                        // Hard shell depth limit for DepthAction (0 = DepthWarningThreshold)
+	DepthWarningOnce      bool                       // Show the depth tip only once per terminal session
+	AppExitStatus         int                        // Application exit status, exported as AUTOCD_EXIT_STATUS (default: 0)
+	ExitIfNonInteractive  bool                       // Without a terminal, exit with AppExitStatus instead of starting the shell
+	ReportDirectory       bool                       // Emit OSC 7 and OSC 9;9 so terminals track the target for new tabs and split panes
+	DisableOldPwd         bool                       // Don't export OLDPWD as the launch directory (by default "cd -" returns there)
+	TerminalTitle         string                     // Window title template, "{dir}"/"{base}" expanded ("" = leave title unchanged)
+	PromptPrefix          string                     // Prefix marking the spawned shell's prompt, e.g. "(myapp) " ("" = unchanged)
+	CDPath                []string                   // Directories exported as CDPATH in the spawned shell, ahead of an inherited CDPATH
+	ShellsFile            string                     // Allowed shells list for overrides under SecurityStrict ("" = /etc/shells)
+	CleanEnvironment      bool                       // Start the shell with a scrubbed environment (env -i semantics)
+	EnvAllowlist          []string                   // Extra variables kept by CleanEnvironment ("NAME" or "PREFIX*")
+	ExtraEnv              map[string]string          // Variables passed to the spawned shell through exec (never written to the script)
+	UnsetEnv              []string                   // Variables removed from the spawned shell's environment ("NAME" or "PREFIX*")
+	TrimOversizedEnv      bool                       // Drop the largest variables instead of failing when exec would hit E2BIG
+	AllowMissingTarget    bool                       // SecurityPermissive only: accept targets that don't exist yet (checked by the script's cd)
+	RefuseAsRoot          bool                       // Return ErrRunningAsRoot instead of spawning a shell as root
+	WarnAsRoot            bool                       // Print a warning before spawning a shell as root
+	SudoHandoff           bool                       // Under sudo, spawn the shell as SUDO_USER (via sudo -u) instead of root
+	ReuseScript           bool                       // Rewrite one stable per-app script instead of creating a new temp file each time
+	ScriptMaxAge          time.Duration              // Scripts refuse to run this long after creation (default: 5m, negative = no check)
+	PreflightScriptDir    bool                       // Test-run a no-op script in the temp dir first (once per process); fall back to XDG_RUNTIME_DIR or a pipe
+	CDFileEnv             string                     // When this variable (e.g. "NNN_TMPFILE") names a file, write "cd '<dir>'" there and exit
+	LastDirPath           string                     // lf/ranger --last-dir-path file: write the plain directory there and exit ("" = $AUTOCD_LAST_DIR_PATH)
+	OutCmdFile            string                     // broot-style --outcmd file: write "cd '<dir>'" (plus follow-up) there and exit
+	OutCmdFD              int                        // Like OutCmdFile but writes to an inherited file descriptor (0 = unused)
+	NativeCD              bool                       // fish/nushell/pwsh: also cd inside the shell at startup so PWD hooks, prompts and history see it
+	PostCommand           string                     // Command run by /bin/sh in the target before the shell starts, as the shell's user, e.g. "git status" (not escaped)
+	OutCmdFollowUp        string                     // Shell command appended after the cd in outcmd mode (not escaped)
+	BackFunction          string                     // Name of a function returning to the launch directory, e.g. "back" ("" = none)
+	BackExits             bool                       // Make the back function exit the nested shell instead of cd'ing
+	RelaunchFunction      string                     // Name of a function re-running the application (os.Args) in the current directory, e.g. "again" ("" = none)
+	ResultWriter          io.Writer                  // Receives a JSON TransitionResult line just before exec (nil = none)
+	ResultFD              int                        // File descriptor (e.g. 3) receiving the JSON result if open (0 = none)
+	NewSession            bool                       // Move to a new process group (keeping the terminal) before exec so supervisors signalling the old group miss the shell
+	DetachSession         bool                       // With NewSession, call setsid instead; the shell then has no controlling terminal or job control
+	DirectExecSameDir     bool                       // Exec the shell without a script (or banner) when the target is already the working directory
+	PendingOutputs        []BufferedOutput           // Buffered writers (e.g. *bufio.Writer) checked for unflushed data before exec
+	RefusePendingWork     bool                       // Fail with ErrPendingWork instead of warning (debug mode) about goroutines or output lost on exec
+	SkipTTYCheck          bool                       // Skip verifying the controlling terminal and stdin before exec
+	RequireTerminalOutput bool                       // Also require stdout and stderr to be terminals (default: redirected output is allowed)
+	ReopenTTY             bool                       // Reopen the checked streams on /dev/tty when they are not terminals instead of failing
+	OpenInFileManager     bool                       // Without a terminal, open the target in the desktop file manager (xdg-open/open) and exit
+	DetectionOrder        []DetectionSource          // Shell detection tiers to try, in order (nil = Override, ShellEnv, Passwd, Fallback)
+	ShellProbeTimeout     time.Duration              // Run candidates with -i -c 'exit 0' and skip those failing within this time (0 = no probe); each probe sources the shell's rc files, so slow rcs slow every transition
+	ShellFunctions        map[string]ShellDefinition // Functions defined in the spawned shell, name → body per dialect
+	ShellAliases          map[string]ShellDefinition // Aliases defined in the spawned shell, name → expansion per dialect
+	ExportShell           bool                       // With a Shell override, export SHELL=<overriding shell> into the spawned shell
+	RevalidateTarget      bool                       // Re-check the target right before exec and apply MissingTarget if it vanished
+	MissingTarget         MissingTargetPolicy        // What RevalidateTarget does with a vanished target (default: abort)
+	FallbackDir           string                     // Directory used by MissingTargetFallbackDir
+	MaxScripts            int                        // Quota on autocd scripts in the temp dir (0 = unlimited)
+	MaxScriptBytes        int64                      // Quota on the total size of those scripts (0 = unlimited)
+	RefuseOverQuota       bool                       // Fail with ErrScriptQuotaExceeded instead of removing the oldest scripts
+	Audit                 AuditSink                  // Record each transition in syslog or the journal (default: none)
+	AuditIdentifier       string                     // Syslog tag / SYSLOG_IDENTIFIER for audit records ("" = "autocd")
+	PolicyFile            string                     // Path policy enforced in addition to /etc/autocd/policy ("" = host policy only)
+	WaitForTarget         time.Duration              // Poll up to this long for a missing target to appear, e.g. during a clone (0 = don't wait)
+	AutomountTimeout      time.Duration              // Keep opening a missing target this long to trigger autofs mounts (0 = don't)
+	CopyToClipboard       bool                       // Copy the final directory to the clipboard via OSC 52 before exec
+	TmuxExport            bool                       // Inside tmux, set AUTOCD_DIR to the target in the session environment for new panes
+	TmuxPaneTitle         string                     // Inside tmux, pane title template, "{dir}"/"{base}" expanded ("" = unchanged)
+	PlainOutput           bool                       // Never write OSC 8 hyperlinks or OSC 133 marks, even on terminals known to support them
+	OnBeforeExec          func(Transition) error     // Called once everything is prepared; an error cancels the transition and is returned
+	Strategies            []Strategy                 // Fallback ladder tried in order until one takes over (nil = exec only)
+	OnStrategy            func(Strategy, error)      // Called as a strategy takes over (nil error) and when one fails
+	SentinelFile          string                     // File written by StrategySentinelFile
+	PrepareTimeout        time.Duration              // Budget for all work before exec; exceeding it returns a recoverable ErrPrepareTimeout (0 = unlimited)
 }
 
 // ErrorType categorizes different types of autocd errors
@@ -36,6 +170,10 @@ const (
 	ErrorScriptGeneration
 	ErrorScriptExecution
 	ErrorSecurityViolation
+	ErrorPathTooLong
+	ErrorTimeout
+	ErrorVetoed
+	ErrorDepthLimit
 )
 
 // AutoCDError provides structured error information
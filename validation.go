@@ -5,12 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 )
 
 // Pre-compiled regex for performance
 var (
 	invalidCharsRegex = regexp.MustCompile(`[\x00-\x1f\x7f]`)
+	lineBreakRegex    = regexp.MustCompile(`[\x00\n\r]`)
 )
 
 // validateTargetPath performs security validation based on level
@@ -21,22 +23,40 @@ func validateTargetPath(path string, level SecurityLevel) (string, error) {
 		return "", fmt.Errorf("invalid path: %w", err)
 	}
 
-	// Check if path exists and is directory
+	if err := statDirectory(absPath); err != nil {
+		return "", err
+	}
+
+	validatedPath, err := validateLevel(absPath, level)
+	if err != nil {
+		return "", err
+	}
+	if err := enforcePolicyFile(hostPolicyFile, validatedPath, false); err != nil {
+		return "", err
+	}
+	return validatedPath, nil
+}
+
+// statDirectory checks that an absolute path exists and is a directory
+func statDirectory(absPath string) error {
 	info, err := os.Stat(absPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", ErrPathNotFound
+			return ErrPathNotFound
 		}
-		return "", fmt.Errorf("failed to stat path: %w", err)
+		return fmt.Errorf("failed to stat path: %w", err)
 	}
 	if !info.IsDir() {
-		return "", ErrPathNotDirectory
+		return ErrPathNotDirectory
 	}
 
 	// Do not require read permission; cd only needs execute permission on Unix.
 	// We intentionally skip a read-access check to allow enterable but non-listable directories.
+	return nil
+}
 
-	// Security level specific validation
+// validateLevel applies the security level specific validation
+func validateLevel(absPath string, level SecurityLevel) (string, error) {
 	switch level {
 	case SecurityStrict:
 		return validateStrict(absPath)
@@ -49,6 +69,63 @@ func validateTargetPath(path string, level SecurityLevel) (string, error) {
 	}
 }
 
+// validateMissingTarget accepts a target that does not exist yet, deferring
+// the existence check to the transition script's cd (SecurityPermissive only)
+func validateMissingTarget(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	validatedPath, _ := validatePermissive(absPath)
+	if err := enforcePolicyFile(hostPolicyFile, validatedPath, false); err != nil {
+		return "", err
+	}
+	return validatedPath, nil
+}
+
+// revalidateTarget checks a validated target again just before exec. When
+// it is no longer a directory, opts.MissingTarget decides what happens:
+// abort with the error, fall back to the nearest existing ancestor, or
+// fall back to opts.FallbackDir.
+func revalidateTarget(validatedPath string, opts *Options) (string, error) {
+	err := statDirectory(validatedPath)
+	if err == nil {
+		return validatedPath, nil
+	}
+
+	switch opts.MissingTarget {
+	case MissingTargetAncestor:
+		return validateTargetPath(existingAncestor(validatedPath), opts.SecurityLevel)
+	case MissingTargetFallbackDir:
+		if opts.FallbackDir == "" {
+			return "", err
+		}
+		fallback, fallbackErr := validateTargetPath(opts.FallbackDir, opts.SecurityLevel)
+		if fallbackErr != nil {
+			return "", fmt.Errorf("fallback directory %s: %w", opts.FallbackDir, fallbackErr)
+		}
+		return fallback, nil
+	default:
+		return "", err
+	}
+}
+
+// existingAncestor returns the closest parent of absPath that is still a
+// directory; the walk ends at the filesystem root
+func existingAncestor(absPath string) string {
+	dir := absPath
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+		if statDirectory(dir) == nil {
+			return dir
+		}
+	}
+}
+
 func validateStrict(path string) (string, error) {
 
 	// Character whitelist for Unix paths
@@ -56,25 +133,56 @@ func validateStrict(path string) (string, error) {
 		return "", ErrSecurityViolation
 	}
 
-	// Length limits (Unix filesystems typically support up to 4096 bytes)
-	if len(path) > 4096 {
-		return "", ErrSecurityViolation
+	// Length limits depend on the platform (PATH_MAX and NAME_MAX)
+	if err := checkPathLength(path); err != nil {
+		return "", err
 	}
 
 	return path, nil
 }
 
+// maxPathLength returns PATH_MAX for the current platform
+func maxPathLength() int {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return 1024
+	case "windows":
+		return 32767 // Extended-length paths
+	default:
+		return 4096
+	}
+}
+
+// maxNameLength returns NAME_MAX (longest single path component)
+func maxNameLength() int {
+	return 255
+}
+
+// checkPathLength enforces platform path and component length limits
+func checkPathLength(path string) error {
+	if limit := maxPathLength(); len(path) > limit {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrPathTooLong, len(path), limit)
+	}
+
+	for _, component := range strings.Split(path, string(filepath.Separator)) {
+		if limit := maxNameLength(); len(component) > limit {
+			return fmt.Errorf("%w: component of %d bytes exceeds the %d byte limit", ErrPathTooLong, len(component), limit)
+		}
+	}
+	return nil
+}
+
 func validateNormal(path string) (string, error) {
 	// Clean the path first
 	cleanPath := filepath.Clean(path)
 
 	// With proper single-quote escaping in scripts, we don't need to block
-	// most shell metacharacters in directory names. Only block the most
-	// dangerous combinations that could break out of quotes.
-
-	// Check for null bytes which can't be in valid paths
-	if strings.Contains(path, "\x00") {
-		return "", ErrSecurityViolation
+	// most shell metacharacters in directory names. Other control
+	// characters (tabs, ESC) are escaped wherever the path is printed;
+	// NUL and line breaks are rejected, as the request-line protocols and
+	// result files cannot carry them.
+	if loc := lineBreakRegex.FindStringIndex(path); loc != nil {
+		return "", fmt.Errorf("%w: %q at byte %d", ErrControlCharacter, path[loc[0]], loc[0])
 	}
 
 	return cleanPath, nil
@@ -85,6 +193,23 @@ func validatePermissive(path string) (string, error) {
 	return filepath.Clean(path), nil
 }
 
+// escapeControlChars makes a path printable for messages, writing control
+// characters as \n, \r, \t or \xHH escapes. Paths without them are
+// returned unchanged.
+func escapeControlChars(path string) string {
+	return invalidCharsRegex.ReplaceAllStringFunc(path, func(c string) string {
+		switch c {
+		case "\n":
+			return `\n`
+		case "\r":
+			return `\r`
+		case "\t":
+			return `\t`
+		}
+		return fmt.Sprintf(`\x%02x`, c[0])
+	})
+}
+
 // isValidUnixPath checks if path contains only valid Unix characters
 func isValidUnixPath(path string) bool {
 	// Unix paths can contain most characters, but we'll be conservative
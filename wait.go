@@ -0,0 +1,75 @@
+package autocd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Polling backoff used while waiting for the target directory to appear
+const (
+	waitInitialInterval = 50 * time.Millisecond
+	waitMaxInterval     = time.Second
+)
+
+// waitForDirectory polls until path is a directory, backing off
+// exponentially, and gives up after timeout. Errors other than a missing
+// path (e.g. a file in the way) end the wait immediately.
+func waitForDirectory(path string, timeout time.Duration) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	return pollDirectory(absPath, timeout, nil)
+}
+
+// triggerAutomount gives an automounter (autofs, systemd.automount) the
+// chance to mount path, which reports ENOENT until something opens it.
+// The path is opened repeatedly until it appears or timeout passes.
+func triggerAutomount(path string, timeout time.Duration) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	return pollDirectory(absPath, timeout, touchPath)
+}
+
+// touchPath opens and closes path; resolving it triggers automount points
+// along the way
+func touchPath(absPath string) {
+	if f, err := os.Open(absPath); err == nil {
+		f.Close()
+	}
+}
+
+// pollDirectory checks absPath with exponential backoff until it is a
+// directory or timeout passes, calling probe (if any) before each check
+func pollDirectory(absPath string, timeout time.Duration, probe func(string)) error {
+	deadline := time.Now().Add(timeout)
+	interval := waitInitialInterval
+	for {
+		if probe != nil {
+			probe(absPath)
+		}
+		err := statDirectory(absPath)
+		if !errors.Is(err, ErrPathNotFound) {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: %s did not appear within %s", ErrPathNotFound, absPath, timeout)
+		}
+		if interval > remaining {
+			interval = remaining
+		}
+		time.Sleep(interval)
+
+		interval *= 2
+		if interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package autocd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test waiting for a directory created by a concurrent job
+func TestWaitForDirectory(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "clone")
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		os.Mkdir(target, 0755)
+	}()
+
+	if err := waitForDirectory(target, 5*time.Second); err != nil {
+		t.Fatalf("Directory should appear while waiting: %v", err)
+	}
+}
+
+// Test the timeout and early exit cases
+func TestWaitForDirectory_Errors(t *testing.T) {
+	base := t.TempDir()
+
+	start := time.Now()
+	err := waitForDirectory(filepath.Join(base, "never"), 120*time.Millisecond)
+	if !errors.Is(err, ErrPathNotFound) || !strings.Contains(err.Error(), "did not appear") {
+		t.Errorf("Expected a timeout wrapping ErrPathNotFound, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Wait overran its timeout: %s", elapsed)
+	}
+
+	file := filepath.Join(base, "file")
+	os.WriteFile(file, nil, 0644)
+	if err := waitForDirectory(file, time.Minute); !errors.Is(err, ErrPathNotDirectory) {
+		t.Errorf("A file in the way should end the wait with ErrPathNotDirectory, got: %v", err)
+	}
+}
+
+// Test the automount trigger loop on plain directories
+func TestTriggerAutomount(t *testing.T) {
+	dir := t.TempDir()
+	if err := triggerAutomount(dir, time.Second); err != nil {
+		t.Errorf("Existing directory should pass immediately: %v", err)
+	}
+
+	err := triggerAutomount(filepath.Join(dir, "share"), 60*time.Millisecond)
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Missing mount should time out with ErrPathNotFound, got: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package autocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Variables telling a wrapped command where to report its final directory
+const (
+	cwdFileEnv = "AUTOCD_CWD_FILE" // Path of a file to write the directory into
+	cwdFDEnv   = "AUTOCD_CWD_FD"   // Descriptor whose last line is the directory
+)
+
+// wrappedCWDFD is the descriptor number the report pipe gets in the child
+const wrappedCWDFD = 3
+
+// pipeDrainTimeout bounds reading the report pipe after the command exits,
+// in case a background grandchild inherited and still holds it open
+const pipeDrainTimeout = 100 * time.Millisecond
+
+// RunAndInherit spawns argv, tracks its working directory and, once it
+// exits, transitions into the directory it ended up in. The program
+// reports the directory by writing it to the file named by
+// AUTOCD_CWD_FILE, or as the last line written to the descriptor in
+// AUTOCD_CWD_FD (3). Without a report, Linux falls back to the last
+// working directory observed in /proc/<pid>/cwd while it ran, if that
+// differs from the caller's own. The
+// program's exit status becomes AppExitStatus; cancelling ctx kills it.
+//
+// On success this function never returns. Otherwise it returns the
+// command's exit status (or 1 if it could not be run) together with the
+// error; ErrNoDirectoryReported means no directory could be determined.
+func RunAndInherit(ctx context.Context, argv []string, opts *Options) (int, error) {
+	if opts == nil {
+		opts = &Options{DebugMode: os.Getenv("AUTOCD_DEBUG") != ""}
+	}
+
+	dir, status, err := runAndCapture(ctx, argv, opts.TempDir)
+	if err != nil {
+		return status, err
+	}
+
+	transition := *opts
+	transition.AppExitStatus = status
+	return status, ExitWithDirectoryAdvanced(dir, &transition)
+}
+
+// runAndCapture runs command with the report channels set up and returns
+// the reported directory and the command's exit status
+func runAndCapture(ctx context.Context, command []string, tempDir string) (string, int, error) {
+	if len(command) == 0 {
+		return "", 1, errors.New("no command given")
+	}
+
+	cwdFile, err := os.CreateTemp(GetTempDir(tempDir), "autocd_cwd_*")
+	if err != nil {
+		return "", 1, fmt.Errorf("failed to create directory report file: %w", err)
+	}
+	cwdFile.Close()
+	defer os.Remove(cwdFile.Name())
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", 1, fmt.Errorf("failed to create directory report pipe: %w", err)
+	}
+	defer r.Close()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{w}
+	cmd.Env = append(os.Environ(),
+		cwdFileEnv+"="+cwdFile.Name(),
+		fmt.Sprintf("%s=%d", cwdFDEnv, wrappedCWDFD),
+	)
+
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return "", 1, fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+	w.Close()
+
+	var (
+		piped []byte
+		wg    sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		piped, _ = io.ReadAll(r)
+	}()
+
+	// Track the working directory for programs that don't report one
+	polled := make(chan string, 1)
+	stopPolling := make(chan struct{})
+	go pollProcessCWD(cmd.Process.Pid, stopPolling, polled)
+
+	status := 0
+	err = cmd.Wait()
+	close(stopPolling)
+	lastSeen := <-polled
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", 1, fmt.Errorf("failed to wait for %s: %w", command[0], err)
+		}
+		status = exitErr.ExitCode()
+	}
+	r.SetReadDeadline(time.Now().Add(pipeDrainTimeout))
+	wg.Wait()
+
+	if data, err := os.ReadFile(cwdFile.Name()); err == nil {
+		if dir := lastLine(string(data)); dir != "" {
+			return dir, status, nil
+		}
+	}
+	if dir := lastLine(string(piped)); dir != "" {
+		return dir, status, nil
+	}
+	if cwd, _ := os.Getwd(); lastSeen != "" && lastSeen != cwd {
+		return lastSeen, status, nil
+	}
+	return "", status, fmt.Errorf("%w: %s", ErrNoDirectoryReported, command[0])
+}
+
+// cwdPollInterval is how often a wrapped command's working directory is sampled
+const cwdPollInterval = 100 * time.Millisecond
+
+// pollProcessCWD samples the working directory of pid until stop is
+// closed, then sends the last value seen ("" if none) on result. It only
+// polls where this is cheap (/proc on Linux).
+func pollProcessCWD(pid int, stop <-chan struct{}, result chan<- string) {
+	lastSeen := ""
+	if !cwdPollable {
+		<-stop
+		result <- lastSeen
+		return
+	}
+
+	ticker := time.NewTicker(cwdPollInterval)
+	defer ticker.Stop()
+	for {
+		if cwd, err := processCWD(pid); err == nil {
+			lastSeen = cwd
+		}
+		select {
+		case <-stop:
+			result <- lastSeen
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\r\n"), "\n")
+	return strings.TrimRight(lines[len(lines)-1], "\r")
+}
@@ -0,0 +1,63 @@
+package autocd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test directory reports through the file and the descriptor
+func TestRunAndCapture(t *testing.T) {
+	dir := t.TempDir()
+
+	got, status, err := runAndCapture(context.Background(), []string{"/bin/sh", "-c", `echo "` + dir + `" > "$AUTOCD_CWD_FILE"; exit 3`}, "")
+	if err != nil || got != dir || status != 3 {
+		t.Errorf("File report: got %q, status %d, err %v", got, status, err)
+	}
+
+	got, status, err = runAndCapture(context.Background(), []string{"/bin/sh", "-c", `echo /ignored >&3; echo "` + dir + `" >&3`}, "")
+	if err != nil || got != dir || status != 0 {
+		t.Errorf("Descriptor report: got %q, status %d, err %v", got, status, err)
+	}
+}
+
+// Test commands that report nothing or cannot run
+func TestRunAndCapture_Errors(t *testing.T) {
+	_, status, err := runAndCapture(context.Background(), []string{"/bin/sh", "-c", "exit 2"}, "")
+	if !errors.Is(err, ErrNoDirectoryReported) || status != 2 {
+		t.Errorf("Expected ErrNoDirectoryReported with status 2, got %v, %d", err, status)
+	}
+
+	if _, _, err := runAndCapture(context.Background(), []string{"/nonexistent/program"}, ""); err == nil {
+		t.Error("Expected an error for a missing program")
+	}
+	if _, _, err := runAndCapture(context.Background(), nil, ""); err == nil {
+		t.Error("Expected an error for an empty command")
+	}
+
+	if line := lastLine("a\n\nb\r\n\n"); line != "b" {
+		t.Errorf("lastLine = %q, want b", line)
+	}
+}
+
+// Test the /proc cwd polling fallback and cancellation
+func TestRunAndCapture_PolledCWD(t *testing.T) {
+	if !cwdPollable {
+		t.Skip("working directory polling not available on this platform")
+	}
+	dir := t.TempDir()
+
+	got, _, err := runAndCapture(context.Background(), []string{"/bin/sh", "-c", `cd "` + dir + `" && sleep 0.3`}, "")
+	if err != nil || got != dir {
+		t.Errorf("Expected polled directory %s, got %q, %v", dir, got, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	runAndCapture(ctx, []string{"/bin/sh", "-c", "exec sleep 10"}, "")
+	if time.Since(start) > 5*time.Second {
+		t.Error("Cancelling the context should stop the command")
+	}
+}